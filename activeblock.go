@@ -1,15 +1,36 @@
 package sputnik
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+	"time"
+)
 
 type activeBlock struct {
 	descriptor BlockDescriptor
 	block      *Block
 	controller *controller
+
+	// Protects block and controller, replaced during restart of the block,
+	// and names of requested configurations
+	lock sync.RWMutex
+
+	// Times of restarts, used by supervisor
+	restarts []time.Time
+
+	// Names of configurations requested by the block
+	confNames map[string]struct{}
 }
 
 func (abl *activeBlock) init(cf ConfFactory) error {
-	err := abl.block.init(cf)
+	var err error
+
+	rcf := abl.recordingFactory(cf)
+
+	pe := protect(abl.descriptor, InitCallback, func() { err = abl.block.init(rcf) })
+	if pe != nil {
+		err = pe
+	}
 
 	if err != nil {
 		err = fmt.Errorf("Init of [%s,%s] failed with error %s", abl.descriptor.Name, abl.descriptor.Responsibility, err.Error())
@@ -20,11 +41,31 @@ func (abl *activeBlock) init(cf ConfFactory) error {
 
 func (abl *activeBlock) finish() {
 	// For interception
-	abl.block.finish(true)
+	protect(abl.descriptor, FinishCallback, func() { abl.block.finish(true) })
+}
+
+// Takes block and names of requested configurations of the new instance
+func (abl *activeBlock) replace(nabl *activeBlock) {
+	abl.lock.Lock()
+	defer abl.lock.Unlock()
+	abl.block = nabl.block
+	abl.confNames = nabl.confNames
 }
 
-func newActiveBlock(bd BlockDescriptor, bl *Block) activeBlock {
-	return activeBlock{bd, bl, nil}
+func (abl *activeBlock) setController(cn *controller) {
+	abl.lock.Lock()
+	defer abl.lock.Unlock()
+	abl.controller = cn
+}
+
+func (abl *activeBlock) currentController() *controller {
+	abl.lock.RLock()
+	defer abl.lock.RUnlock()
+	return abl.controller
+}
+
+func newActiveBlock(bd BlockDescriptor, bl *Block) *activeBlock {
+	return &activeBlock{descriptor: bd, block: bl}
 }
 
 type activeBlocks []*activeBlock
@@ -37,3 +78,80 @@ func (abs activeBlocks) getABl(resp string) (*activeBlock, bool) {
 	}
 	return nil, false
 }
+
+// Active blocks of the process shared by initiator and controllers.
+// The first block is always initiator.
+// Changed by initiator during runtime add/remove of the blocks.
+type blocksSet struct {
+	lock sync.RWMutex
+	abls activeBlocks
+	// Subscriptions of the blocks
+	bus *topics
+	// Responsibilities of infrastructure blocks
+	infra map[string]bool
+	// Optional sink for tracing of messages
+	sink TraceSink
+	// Optional responsibility of dead-letter block
+	deadLetters string
+	// Closed after exit from the main loop of initiator
+	stopped <-chan struct{}
+	// Registry of the process
+	reg *Registry
+}
+
+func newBlocksSet(abls activeBlocks, infra ...string) *blocksSet {
+	bs := &blocksSet{abls: abls, bus: newTopics(), infra: make(map[string]bool)}
+	for _, resp := range infra {
+		bs.infra[resp] = true
+	}
+	return bs
+}
+
+func (bs *blocksSet) isInfrastructure(resp string) bool {
+	return bs.infra[resp]
+}
+
+func (bs *blocksSet) getABl(resp string) (*activeBlock, bool) {
+	bs.lock.RLock()
+	defer bs.lock.RUnlock()
+	return bs.abls.getABl(resp)
+}
+
+// Snapshot of active blocks
+func (bs *blocksSet) blocks() activeBlocks {
+	bs.lock.RLock()
+	defer bs.lock.RUnlock()
+	result := make(activeBlocks, len(bs.abls))
+	copy(result, bs.abls)
+	return result
+}
+
+// Snapshot of active blocks without initiator
+func (bs *blocksSet) appBlocks() activeBlocks {
+	return bs.blocks()[1:]
+}
+
+func (bs *blocksSet) initiator() *controller {
+	bs.lock.RLock()
+	defer bs.lock.RUnlock()
+	return bs.abls[0].currentController()
+}
+
+func (bs *blocksSet) add(abl *activeBlock) {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+	bs.abls = append(bs.abls, abl)
+}
+
+func (bs *blocksSet) remove(resp string) {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+
+	result := make(activeBlocks, 0, len(bs.abls))
+	for _, abl := range bs.abls {
+		if abl.descriptor.Responsibility != resp {
+			result = append(result, abl)
+		}
+	}
+	bs.abls = result
+}
@@ -0,0 +1,80 @@
+package sputnik
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Errors returned by Ask
+var (
+	ErrNoMsgHandler  = errors.New("block does not have OnMsg callback")
+	ErrBlockFinished = errors.New("block was finished")
+)
+
+// Ask sends copy of msg to controlled block and waits for the reply.
+// Reply channel is stored within message under reserved key "__reply",
+// OnMsg of the block answers using Reply:
+//
+//	func (b *myBlock) onMsg(msg sputnik.Msg) {
+//		if sputnik.IsRequest(msg) {
+//			sputnik.Reply(msg, sputnik.Msg{"status": "ok"})
+//		}
+//	}
+//
+// Use context.WithTimeout for limiting of the waiting.
+func (cn *controller) Ask(ctx context.Context, msg Msg) (Msg, error) {
+	return cn.askFrom(ctx, cn.self(), msg)
+}
+
+func (cn *controller) askFrom(ctx context.Context, from sender, msg Msg) (Msg, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("Ask [%s]: nil message", cn.descriptor.Responsibility)
+	}
+
+	reply := make(chan Msg, 1)
+
+	req := copymsg(msg)
+	req["__reply"] = reply
+
+	if err := cn.deliver(ctx, from, req); err != nil {
+		return nil, fmt.Errorf("Ask [%s]: %w", cn.descriptor.Responsibility, err)
+	}
+
+	select {
+	case resp := <-reply:
+		return resp, nil
+	case <-cn.mpr.done:
+		// Reply could be sent just before finish
+		select {
+		case resp := <-reply:
+			return resp, nil
+		default:
+		}
+		return nil, fmt.Errorf("Ask [%s]: %w", cn.descriptor.Responsibility, ErrBlockFinished)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("Ask [%s]: %w", cn.descriptor.Responsibility, ctx.Err())
+	}
+}
+
+// IsRequest returns true for message sent by Ask
+func IsRequest(req Msg) bool {
+	_, ok := req["__reply"].(chan Msg)
+	return ok
+}
+
+// Reply sends resp to the block waiting within Ask.
+// Returns false if req was not sent by Ask or reply was already sent.
+func Reply(req Msg, resp Msg) bool {
+	reply, ok := req["__reply"].(chan Msg)
+	if !ok {
+		return false
+	}
+
+	select {
+	case reply <- resp:
+		return true
+	default:
+		return false
+	}
+}
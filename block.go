@@ -1,12 +1,99 @@
 package sputnik
 
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 // Block has Name (analog of golang type) and Responsibility (instance of specific block)
 // This separation allows to run simultaneously blocks with the same Name.
 // Other possibility - blocks with different name but with the same responsibility,
 // e.g. different implementation of "finisher" depends on environment.
+//
+// Compatibility note: descriptor was extended by optional fields, so
+//   - use keyed literals: BlockDescriptor{Name: "dumb", Responsibility: "1"}
+//   - descriptor is not comparable (DependsOn, Options), use ID() as key of the map
 type BlockDescriptor struct {
 	Name           string
 	Responsibility string
+
+	// Optional deadline for Finish of the block during shutdown.
+	// Overrides default set by WithFinishTimeout.
+	// In blocks.json - duration string, e.g. "5s".
+	FinishTimeout time.Duration `json:",omitempty"`
+
+	// Optional restart policy, used by supervisor
+	Restart RestartPolicy
+
+	// Optional responsibilities of blocks, which should be
+	// initialized before this block and finished after it
+	DependsOn []string `json:",omitempty"`
+
+	// Optional per-instance options, passed to ParamBlockFactory.
+	// In blocks.json - "options" object.
+	Options BlockOptions `json:",omitempty"`
+}
+
+// Comparable identity of the block
+type BlockID struct {
+	Name           string
+	Responsibility string
+}
+
+func (bd BlockDescriptor) ID() BlockID {
+	return BlockID{Name: bd.Name, Responsibility: bd.Responsibility}
+}
+
+func (bd BlockDescriptor) MarshalJSON() ([]byte, error) {
+	type plain BlockDescriptor
+	return json.Marshal(struct {
+		plain
+		FinishTimeout jsonDuration `json:",omitempty"`
+	}{plain(bd), jsonDuration(bd.FinishTimeout)})
+}
+
+func (bd *BlockDescriptor) UnmarshalJSON(data []byte) error {
+	type plain BlockDescriptor
+	aux := struct {
+		*plain
+		FinishTimeout jsonDuration `json:",omitempty"`
+	}{plain: (*plain)(bd)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	bd.FinishTimeout = time.Duration(aux.FinishTimeout)
+	return nil
+}
+
+// Duration within json: string ("1m30s") or number of nanoseconds
+type jsonDuration time.Duration
+
+func (d jsonDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch val := v.(type) {
+	case float64:
+		*d = jsonDuration(val)
+	case string:
+		pd, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		*d = jsonDuration(pd)
+	default:
+		return fmt.Errorf("wrong duration %s", string(data))
+	}
+	return nil
 }
 
 const (
@@ -21,15 +108,21 @@ const (
 
 // Block has set of the callbacks:
 //   - mandatory:	Init|Run|Finish
-//   - optional:	OnServerConnect|OnServerDisconnect|OnMsg
+//   - optional:	OnServerConnect|OnServerDisconnect|OnMsg|OnConfigChange
 //
 // Init callback is executed by sputnik once during initialization.
-// Blocks are initialized in sequenced order according to configuration.
+// Blocks are initialized in sequenced order according to dependencies (DependsOn)
+// and configuration.
 // Some rules :
 //   - don't run hard processing within Init
 //   - don't work with server till call of OnServerConnect
 type Init func(cf ConfFactory) error
 
+// Optional Start callback is executed by sputnik on the goroutine of Run
+// before call of Run. Return from Start means readiness of the block.
+// Use it for long preparation of the block after Init (e.g. warm up of caches).
+type Start func(communicator BlockCommunicator)
+
 // After successful initialization of ALL blocks, sputnik creates goroutine and calls Run
 // Other callbacks will be executed on another goroutines
 // After Run block is allowed to negotiate with another blocks of the process
@@ -40,7 +133,9 @@ type Run func(communicator BlockCommunicator)
 //   - during initialization  of the process if init of another block failed (init == true)
 //   - during shutdown of the process (init == false)
 //
-// Blocks are finished in reverse of initialization order.
+// Blocks are finished in reverse of initialization order:
+// during shutdown Finish of the block is called after return from Finish
+// of all blocks depending on it (DependsOn).
 type Finish func(init bool)
 
 // Optional OnServerConnect callback is executed by sputnik after successful
@@ -51,6 +146,11 @@ type OnServerConnect func(connection ServerConnection)
 // connected server disconnects.
 type OnServerDisconnect func()
 
+// Optional OnConfigChange callback is executed by sputnik after change of
+// configuration, previously requested by the block from ConfFactory.
+// Use cf for re-reading of the configuration.
+type OnConfigChange func(confName string, cf ConfFactory)
+
 // Because asynchronous nature of blocks, negotiation between blocks done using 'messages'
 // Message may be command|query|event|update|...
 // Developers of blocks should agree on content of messages.
@@ -61,7 +161,8 @@ type Msg map[string]any
 
 // Optional OnMsg callback is executed by sputnik as result of receiving Msg.
 // Block can send message to itself.
-// Unlike other callbacks, OnMsg called sequentially one by one from the same goroutine.
+// Unlike other callbacks, OnMsg called sequentially one by one from the same goroutine,
+// unless the block uses pool of workers (see WithWorkers).
 type OnMsg func(msg Msg)
 
 // Simplified Block life cycle:
@@ -80,6 +181,15 @@ type Block struct {
 	onConnect    OnServerConnect
 	onDisconnect OnServerDisconnect
 	onMsg        OnMsg
+	onConfChange OnConfigChange
+	start        Start
+	manualReady  bool
+	readyNotice  bool
+	mbCapacity   int
+	mbPolicy     OverflowPolicy
+	mws          []Middleware
+	workers      int
+	partKey      PartitionKey
 }
 
 type BlockOption func(b *Block)
@@ -128,6 +238,63 @@ func WithOnMsg(f OnMsg) BlockOption {
 	}
 }
 
+func WithOnConfigChange(f OnConfigChange) BlockOption {
+	return func(b *Block) {
+		b.onConfChange = f
+	}
+}
+
+func WithStart(f Start) BlockOption {
+	return func(b *Block) {
+		b.start = f
+	}
+}
+
+// Block reports own readiness by call of BlockCommunicator.Ready
+func WithManualReady() BlockOption {
+	return func(b *Block) {
+		b.manualReady = true
+	}
+}
+
+// OnMsg of the block receives message with name AllReadyMsgName
+// after readiness of the process
+func WithReadyNotice() BlockOption {
+	return func(b *Block) {
+		b.readyNotice = true
+	}
+}
+
+// Bounded mailbox of the block: capacity and reaction on overflow.
+// By default mailbox is unbounded.
+func WithMailbox(capacity int, policy OverflowPolicy) BlockOption {
+	return func(b *Block) {
+		b.mbCapacity = capacity
+		b.mbPolicy = policy
+	}
+}
+
+// PartitionKey returns key of the message for concurrent OnMsg (see WithWorkers)
+type PartitionKey func(msg Msg) string
+
+// OnMsg of the block is called concurrently on n goroutines.
+// Messages with the same key are processed sequentially in order of the mailbox,
+// messages with different keys - in parallel.
+// nil key - every message may be processed by any free worker without ordering.
+//
+// With key every worker has own queue, busy worker does not delay messages of other workers.
+// Capacity of the mailbox (see WithMailbox) is divided between queues of workers,
+// overflow policy is applied to the queue of the worker.
+//
+// OnMsg (and middlewares of the block) should be safe for concurrent use.
+// Messages sent by the worker during OnMsg continue the chain of the processed message (see tracing).
+func WithWorkers(n int, key PartitionKey) BlockOption {
+	return func(b *Block) {
+		b.workers = n
+		b.partKey = key
+	}
+}
+
 // 1 - Check presence of mandatory callbacks: init|run|finish
 // 2 - if oncdenabled == false, callbacks onConnect|onDisconnect should be nil
 func (bl *Block) isValid(oncdenabled bool) bool {
@@ -153,10 +320,63 @@ type BlockCommunicator interface {
 	// Identification of controlled block
 	Descriptor() BlockDescriptor
 
+	// Report readiness of controlled block.
+	// Used by blocks created with WithManualReady option.
+	Ready()
+
 	// Asynchronously send message to controlled block
 	// true is returned if
 	//  - controlled block has OnMsg callback
 	//  - recipient of messages was not cancelled
 	//  - msg != nil
+	//  - message was not rejected by bounded mailbox (see WithMailbox)
 	Send(msg Msg) bool
+
+	// Send copy of the message with priority.
+	// Messages with PriorityHigh are processed before other messages
+	// and are not limited by capacity of the mailbox.
+	SendWithPriority(msg Msg, p Priority) bool
+
+	// Send with deadline for waiting on full mailbox (OverflowBlock)
+	// Error is returned if
+	//  - controlled block has not OnMsg callback (ErrNoMsgHandler)
+	//  - controlled block was finished (ErrBlockFinished)
+	//  - message was rejected (ErrMailboxFull)
+	//  - ctx was cancelled or deadline exceeded
+	SendContext(ctx context.Context, msg Msg) error
+
+	// Counters of the mailbox of controlled block
+	Mailbox() MailboxStats
+
+	// Timers of controlled block.
+	// All timers are cancelled when controlled block is finished.
+	//
+	// Send copy of msg after d
+	SendAfter(d time.Duration, msg Msg) (cancel func())
+	// Send copy of msg every interval
+	SendEvery(interval time.Duration, msg Msg) (cancel func())
+	// Send copy of msg according to cron expression "minute hour day-of-month month day-of-week"
+	SendCron(spec string, msg Msg) (cancel func(), err error)
+
+	// Send message to controlled block and wait for the reply (see Reply)
+	// Error is returned if
+	//  - controlled block has not OnMsg callback (ErrNoMsgHandler)
+	//  - controlled block was finished (ErrBlockFinished)
+	//  - ctx was cancelled or deadline exceeded
+	Ask(ctx context.Context, msg Msg) (Msg, error)
+
+	// Subscribe controlled block to the topic (see Publish)
+	// false is returned if controlled block has not OnMsg callback
+	Subscribe(topic string) bool
+
+	// Remove subscription of controlled block
+	Unsubscribe(topic string)
+
+	// Send copy of msg to all blocks subscribed to the topic
+	// Returns number of recipients
+	Publish(topic string, msg Msg) int
+
+	// Send copy of msg to every application block with OnMsg callback selected by filter
+	// Returns result of delivery per responsibility of recipient
+	Broadcast(msg Msg, filter BroadcastFilter) map[string]bool
 }
@@ -0,0 +1,139 @@
+package sputnik
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Interface-based alternative to callback options.
+// Every method has the same semantic as related callback (see Init, Run, Finish...)
+
+type Initer interface {
+	Init(cf ConfFactory) error
+}
+
+type Runner interface {
+	Run(communicator BlockCommunicator)
+}
+
+type Finisher interface {
+	Finish(init bool)
+}
+
+// Optional
+type ConnectAware interface {
+	OnServerConnect(connection ServerConnection)
+	OnServerDisconnect()
+}
+
+// Optional
+type MsgHandler interface {
+	OnMsg(msg Msg)
+}
+
+// Optional
+type ConfigChangeAware interface {
+	OnConfigChange(confName string, cf ConfFactory)
+}
+
+// Optional
+type Starter interface {
+	Start(communicator BlockCommunicator)
+}
+
+// Blocker - mandatory part of interface-based block
+type Blocker interface {
+	Initer
+	Runner
+	Finisher
+}
+
+// BlockFrom creates Block from obj, which implements Initer, Runner and Finisher
+// and optionally ConnectAware, MsgHandler, ConfigChangeAware and Starter.
+// Additional options (e.g. WithManualReady) are applied after methods of obj.
+//
+// Example:
+//
+//	type receiver struct{...}
+//
+//	func (r *receiver) Init(cf sputnik.ConfFactory) error {...}
+//	func (r *receiver) Run(bc sputnik.BlockCommunicator) {...}
+//	func (r *receiver) Finish(init bool) {...}
+//	func (r *receiver) OnMsg(msg sputnik.Msg) {...}
+//
+//	blk, err := sputnik.BlockFrom(new(receiver))
+func BlockFrom(obj any, opts ...BlockOption) (*Block, error) {
+	if obj == nil {
+		return nil, fmt.Errorf("BlockFrom: nil object")
+	}
+
+	var missed []string
+	bopts := make([]BlockOption, 0, 8)
+
+	if i, ok := obj.(Initer); ok {
+		bopts = append(bopts, WithInit(i.Init))
+	} else {
+		missed = append(missed, "Init")
+	}
+	if r, ok := obj.(Runner); ok {
+		bopts = append(bopts, WithRun(r.Run))
+	} else {
+		missed = append(missed, "Run")
+	}
+	if f, ok := obj.(Finisher); ok {
+		bopts = append(bopts, WithFinish(f.Finish))
+	} else {
+		missed = append(missed, "Finish")
+	}
+
+	if len(missed) > 0 {
+		return nil, fmt.Errorf("BlockFrom: %T does not implement %s", obj, strings.Join(missed, "|"))
+	}
+
+	if ca, ok := obj.(ConnectAware); ok {
+		bopts = append(bopts, WithOnConnect(ca.OnServerConnect), WithOnDisconnect(ca.OnServerDisconnect))
+	}
+	if mh, ok := obj.(MsgHandler); ok {
+		bopts = append(bopts, WithOnMsg(mh.OnMsg))
+	}
+	if cca, ok := obj.(ConfigChangeAware); ok {
+		bopts = append(bopts, WithOnConfigChange(cca.OnConfigChange))
+	}
+	if st, ok := obj.(Starter); ok {
+		bopts = append(bopts, WithStart(st.Start))
+	}
+
+	return NewBlock(append(bopts, opts...)...), nil
+}
+
+// Constraint for pointer to struct, which implements Blocker
+type BlockerPtr[T any] interface {
+	*T
+	Blocker
+}
+
+// RegisterType registers factory, which creates new instance of T for every block.
+// Options of the descriptor (see BlockOptions) are decoded to the created instance.
+//
+//	err := sputnik.RegisterType[receiver](reg, "syslogreceiver")
+func RegisterType[T any, PT BlockerPtr[T]](r *Registry, name string) error {
+	return r.RegisterParam(name, typeFactory[T, PT])
+}
+
+// RegisterBlockType registers T in the global registry of the process.
+// Same rules as for RegisterBlockFactory.
+func RegisterBlockType[T any, PT BlockerPtr[T]](name string) {
+	if err := RegisterType[T, PT](DefaultRegistry(), name); err != nil {
+		panic(err)
+	}
+}
+
+func typeFactory[T any, PT BlockerPtr[T]](bd BlockDescriptor, opts BlockOptions) (*Block, error) {
+	obj := PT(new(T))
+
+	if err := opts.Decode(obj); err != nil {
+		return nil, err
+	}
+
+	return BlockFrom(obj)
+}
@@ -0,0 +1,284 @@
+package sputnik
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BlocksManager allows to add and remove blocks of running process.
+// It's implemented by communicator of initiator:
+//
+//	ibc, _ := bc.Communicator(sputnik.InitiatorResponsibility)
+//	bm, _ := ibc.(sputnik.BlocksManager)
+//	err := bm.AddBlock(sputnik.BlockDescriptor{Name: "syslogreceiver", Responsibility: "tenant2receiver"})
+//
+// Both calls are synchronous, don't call them from Run or Finish of the removed block.
+// Error is returned if the process finishes before completion of the call.
+type BlocksManager interface {
+	// Creates, initializes (on own goroutine) and runs new block.
+	// If server is connected, OnServerConnect of the block is called.
+	// All blocks from DependsOn of the new block should be active.
+	AddBlock(bd BlockDescriptor) error
+
+	// Finishes the block and removes it from the process.
+	// Infrastructure blocks and blocks other blocks depend on cannot be removed.
+	RemoveBlock(resp string) error
+}
+
+var _ BlocksManager = &controller{}
+
+func (cn *controller) AddBlock(bd BlockDescriptor) error {
+	msg := make(Msg)
+	msg["__name"] = addBlockMsg
+	msg["__descr"] = bd
+	return cn.manageBlocks(msg)
+}
+
+func (cn *controller) RemoveBlock(resp string) error {
+	msg := make(Msg)
+	msg["__name"] = removeBlockMsg
+	msg["__resp"] = resp
+	return cn.manageBlocks(msg)
+}
+
+// Sends request to initiator and waits for the result
+func (cn *controller) manageBlocks(msg Msg) error {
+	if cn.descriptor.Responsibility != InitiatorResponsibility {
+		return fmt.Errorf("blocks are managed only via communicator of %s", InitiatorResponsibility)
+	}
+
+	result := make(chan error, 1)
+	msg["__result"] = result
+
+	if !cn.Send(msg) {
+		return errProcessFinished
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-cn.actBlks.stopped:
+	}
+
+	select {
+	case err := <-result:
+		return err
+	default:
+		return errProcessFinished
+	}
+}
+
+var errProcessFinished = errors.New("process is finished")
+
+// Validates request and initializes new block on own goroutine,
+// main loop is not blocked by Init of the block.
+func (inr *initiator) processAddBlock(m Msg) {
+	result, _ := m["__result"].(chan error)
+	bd, _ := m["__descr"].(BlockDescriptor)
+
+	if err := inr.canAdd(bd); err != nil {
+		result <- err
+		return
+	}
+
+	inr.adding[bd.Responsibility] = true
+
+	go func() {
+		abl, err := inr.sputnik.createByDescr(bd)
+		if err == nil {
+			err = inr.initBlock(abl)
+		}
+		if err != nil {
+			abl = nil
+		}
+
+		am := blockaddedmsg(abl, bd, err, result)
+		if inr.q.put(context.Background(), am, PriorityHigh) != nil {
+			inr.discardMsg(am)
+		}
+	}()
+}
+
+func (inr *initiator) canAdd(bd BlockDescriptor) error {
+	if inr.finishStarted {
+		return fmt.Errorf("process is finishing")
+	}
+
+	if _, exists := inr.actBlks.getABl(bd.Responsibility); exists || inr.adding[bd.Responsibility] {
+		return fmt.Errorf("block with responsibility %s already exists", bd.Responsibility)
+	}
+
+	return inr.checkDependencies(bd)
+}
+
+func (inr *initiator) checkDependencies(bd BlockDescriptor) error {
+	for _, dep := range bd.DependsOn {
+		if !inr.isActive(dep) {
+			return fmt.Errorf("block [%s,%s] depends on not active block %s", bd.Name, bd.Responsibility, dep)
+		}
+	}
+	return nil
+}
+
+// Runs initialized block.
+// Block is finished if process started shutdown or dependencies were removed during Init.
+func (inr *initiator) processBlockAdded(m Msg) {
+	result, _ := m["__result"].(chan error)
+	bd, _ := m["__descr"].(BlockDescriptor)
+	abl, _ := m["__abl"].(*activeBlock)
+	err, _ := m["__err"].(error)
+
+	delete(inr.adding, bd.Responsibility)
+
+	if err != nil {
+		result <- err
+		return
+	}
+
+	if inr.finishStarted {
+		err = fmt.Errorf("process is finishing")
+	} else {
+		err = inr.checkDependencies(bd)
+	}
+
+	if err != nil {
+		go inr.finishInitialized(abl)
+		result <- err
+		return
+	}
+
+	inr.actBlks.add(abl)
+	attachController(bd.Responsibility, inr.actBlks)
+
+	inr.runBlock(abl)
+
+	if inr.connected {
+		inr.connectBlock(abl, inr.conn)
+	}
+
+	result <- nil
+}
+
+// Called for messages discarded after exit from the main loop:
+// pending requests of BlocksManager are failed, initialized
+// (added or restarted) blocks are finished
+func (inr *initiator) discardMsg(m Msg) {
+	if abl, _ := m["__abl"].(*activeBlock); abl != nil {
+		inr.finishInitialized(abl)
+	}
+
+	result, isRequest := m["__result"].(chan error)
+	if !isRequest {
+		return
+	}
+
+	select {
+	case result <- errProcessFinished:
+	default:
+	}
+}
+
+func (inr *initiator) isActive(resp string) bool {
+	if resp == InitiatorResponsibility {
+		return true
+	}
+
+	abl, exists := inr.actBlks.getABl(resp)
+	if !exists {
+		return false
+	}
+
+	return !inr.isFinishing(abl) && !inr.isFinished(resp)
+}
+
+func (inr *initiator) processRemoveBlock(m Msg) {
+	result, _ := m["__result"].(chan error)
+	resp, _ := m["__resp"].(string)
+
+	if err := inr.removeBlock(resp, result); err != nil {
+		result <- err
+	}
+}
+
+// Starts finish of the block, result is sent after finish
+func (inr *initiator) removeBlock(resp string, result chan error) error {
+	if inr.finishStarted {
+		return fmt.Errorf("process is finishing")
+	}
+
+	if inr.isInfrastructure(resp) {
+		return fmt.Errorf("infrastructure block %s cannot be removed", resp)
+	}
+
+	abl, exists := inr.actBlks.getABl(resp)
+	if !exists {
+		return fmt.Errorf("block with responsibility %s does not exist", resp)
+	}
+
+	if _, removing := inr.removals[resp]; removing {
+		return fmt.Errorf("block %s is already removing", resp)
+	}
+
+	for _, dabl := range inr.actBlks.appBlocks() {
+		for _, dep := range dabl.descriptor.DependsOn {
+			if dep == resp {
+				return fmt.Errorf("block %s depends on %s", dabl.descriptor.Responsibility, resp)
+			}
+		}
+	}
+
+	inr.removals[resp] = result
+	delete(inr.restarting, resp)
+
+	if inr.isFinished(resp) {
+		inr.completeRemoval(resp, nil)
+		return nil
+	}
+
+	if !inr.isFinishing(abl) {
+		inr.finishBlock(abl)
+	}
+
+	return nil
+}
+
+// Removes finished block from the process
+func (inr *initiator) completeRemoval(resp string, err error) {
+	result, removing := inr.removals[resp]
+	if !removing {
+		return
+	}
+
+	delete(inr.removals, resp)
+
+	inr.actBlks.remove(resp)
+
+	inr.Lock()
+	delete(inr.finished, resp)
+	inr.Unlock()
+	delete(inr.finishing, resp)
+	delete(inr.restarting, resp)
+	delete(inr.ready, resp)
+	inr.forgetAbandoned(resp)
+
+	result <- err
+
+	inr.checkReadiness()
+}
+
+const (
+	addBlockMsg    = "addBlock"
+	blockAddedMsg  = "blockAdded"
+	removeBlockMsg = "removeBlock"
+)
+
+func blockaddedmsg(abl *activeBlock, bd BlockDescriptor, err error, result chan error) Msg {
+	msg := make(Msg)
+	msg["__name"] = blockAddedMsg
+	msg["__abl"] = abl
+	msg["__descr"] = bd
+	msg["__err"] = err
+	msg["__result"] = result
+	return msg
+}
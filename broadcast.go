@@ -0,0 +1,52 @@
+package sputnik
+
+import "strings"
+
+// Selection of recipients of Broadcast.
+// Zero value - all blocks.
+type BroadcastFilter struct {
+	// Name of the block
+	Name string
+	// Prefix of responsibility of the block
+	RespPrefix string
+}
+
+func (bf BroadcastFilter) match(bd BlockDescriptor) bool {
+	if bf.Name != "" && bf.Name != bd.Name {
+		return false
+	}
+	return strings.HasPrefix(bd.Responsibility, bf.RespPrefix)
+}
+
+// Broadcast sends copy of msg to every application block with OnMsg callback,
+// selected by filter. Infrastructure blocks (initiator, finisher, connector)
+// don't receive broadcasts.
+// Returns result of delivery per responsibility of recipient.
+//
+//	res := bc.Broadcast(sputnik.Msg{"cmd": "flush"}, sputnik.BroadcastFilter{Name: "syslogpublisher"})
+func (cn *controller) Broadcast(msg Msg, filter BroadcastFilter) map[string]bool {
+	return cn.actBlks.broadcast(cn.self(), msg, filter)
+}
+
+func (bs *blocksSet) broadcast(from sender, msg Msg, filter BroadcastFilter) map[string]bool {
+	result := make(map[string]bool)
+
+	if msg == nil {
+		return result
+	}
+
+	for _, abl := range bs.appBlocks() {
+		if bs.isInfrastructure(abl.descriptor.Responsibility) || !filter.match(abl.descriptor) {
+			continue
+		}
+
+		cn := abl.currentController()
+		if cn == nil || cn.block.onMsg == nil {
+			continue
+		}
+
+		result[abl.descriptor.Responsibility] = cn.sendFrom(from, copymsg(msg))
+	}
+
+	return result
+}
@@ -25,7 +25,7 @@ type ServerConnector interface {
 }
 
 func ConnectorDescriptor() BlockDescriptor {
-	return BlockDescriptor{DefaultConnectorName, DefaultConnectorResponsibility}
+	return BlockDescriptor{Name: DefaultConnectorName, Responsibility: DefaultConnectorResponsibility}
 }
 
 func init() {
@@ -69,6 +69,7 @@ func (c *connector) init(cf ConfFactory) error {
 	c.next = c.connect
 	c.mc = make(chan Msg, 1)
 	c.bgfin = make(chan struct{}, 1)
+	c.endfin = make(chan struct{}, 1)
 
 	return nil
 }
@@ -79,7 +80,6 @@ func (c *connector) run(self BlockCommunicator) {
 	c.cbc = self
 	c.ibc, _ = self.Communicator(InitiatorResponsibility)
 
-	c.endfin = make(chan struct{}, 1)
 	defer close(c.endfin)
 
 	enableloop := false
@@ -1,25 +1,45 @@
 package sputnik
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
 var _ BlockCommunicator = &controller{}
 
 type controller struct {
 	descriptor BlockDescriptor
 	block      *Block
-	actBlks    activeBlocks
+	actBlks    *blocksSet
 	mpr        *msgProcessor
+	timers     *blockTimers
+
+	// Causes of messages sent on goroutines of running OnMsg (see tracing)
+	causes   sync.Map
+	handlers atomic.Int32
 }
 
-func attachController(resp string, actBlks activeBlocks) {
+func attachController(resp string, actBlks *blocksSet) {
 	abl, _ := actBlks.getABl(resp)
 	cn := new(controller)
 	cn.descriptor = abl.descriptor
 	cn.block = abl.block
-	cn.mpr = newMsgProcessor(cn.block.onMsg)
+	cn.mpr = newMsgProcessor(cn.processMsg, abl.block.mbCapacity, abl.block.mbPolicy, abl.block.workers, cn.partitionKey())
+	cn.mpr.setDiscard(cn.undelivered)
+	cn.timers = newBlockTimers()
 	cn.actBlks = actBlks
-	abl.controller = cn
+	abl.setController(cn)
 }
 
 func (cn *controller) Communicator(resp string) (bc BlockCommunicator, exists bool) {
+	return cn.communicatorFrom(cn.self(), resp)
+}
+
+// With trace sink communicator of another block remembers the sender (see tracing)
+func (cn *controller) communicatorFrom(from sender, resp string) (bc BlockCommunicator, exists bool) {
 
 	abl, exists := cn.actBlks.getABl(resp)
 
@@ -27,7 +47,13 @@ func (cn *controller) Communicator(resp string) (bc BlockCommunicator, exists bo
 		return nil, false
 	}
 
-	return abl.controller, true
+	target := abl.currentController()
+
+	if !target.traced() || (target == from.controller && from.cause == nil) {
+		return target, true
+	}
+
+	return &peer{controller: target, from: from}, true
 }
 
 func (cn *controller) Descriptor() BlockDescriptor {
@@ -35,16 +61,72 @@ func (cn *controller) Descriptor() BlockDescriptor {
 }
 
 func (cn *controller) Send(msg Msg) bool {
+	return cn.sendFrom(cn.self(), msg)
+}
+
+func (cn *controller) sendFrom(from sender, msg Msg) bool {
 	if msg == nil {
+		cn.deadLetter(from.responsibility(), nil, errNilMsg)
 		return false
 	}
 
-	if cn.block.onMsg == nil {
-		return false
+	return cn.deliver(context.Background(), from, msg) == nil
+}
+
+func (cn *controller) SendWithPriority(msg Msg, p Priority) bool {
+	return cn.sendWithPriorityFrom(cn.self(), msg, p)
+}
+
+func (cn *controller) sendWithPriorityFrom(from sender, msg Msg, p Priority) bool {
+	if msg == nil || p == PriorityNormal {
+		return cn.sendFrom(from, msg)
+	}
+
+	m := copymsg(msg)
+	m["__priority"] = p
+
+	return cn.sendFrom(from, m)
+}
+
+func (cn *controller) SendContext(ctx context.Context, msg Msg) error {
+	return cn.sendContextFrom(ctx, cn.self(), msg)
+}
+
+func (cn *controller) sendContextFrom(ctx context.Context, from sender, msg Msg) error {
+	if msg == nil {
+		cn.deadLetter(from.responsibility(), nil, errNilMsg)
+		return fmt.Errorf("SendContext [%s]: %w", cn.descriptor.Responsibility, errNilMsg)
 	}
-	sok := cn.mpr.submit(msg)
 
-	return sok
+	if err := cn.deliver(ctx, from, msg); err != nil {
+		return fmt.Errorf("SendContext [%s]: %w", cn.descriptor.Responsibility, err)
+	}
+	return nil
+}
+
+var errNilMsg = errors.New("nil message")
+
+// Puts stamped copy of the message to the mailbox of controlled block.
+// Undeliverable message is sent to dead-letter block.
+func (cn *controller) deliver(ctx context.Context, from sender, msg Msg) error {
+	m := from.stamp(msg)
+
+	err := ErrNoMsgHandler
+	if cn.block.onMsg != nil {
+		err = cn.mpr.submitContext(ctx, m)
+	}
+
+	cn.trace(m, err == nil)
+
+	if errors.Is(err, ErrNoMsgHandler) || errors.Is(err, ErrBlockFinished) || errors.Is(err, ErrMailboxFull) {
+		cn.deadLetter(from.responsibility(), m, err)
+	}
+
+	return err
+}
+
+func (cn *controller) Mailbox() MailboxStats {
+	return cn.mpr.stats()
 }
 
 func (cn *controller) ServerConnected(sc ServerConnection) bool {
@@ -52,7 +134,7 @@ func (cn *controller) ServerConnected(sc ServerConnection) bool {
 		return false
 	}
 
-	go cn.block.onConnect(sc)
+	go cn.safe(OnConnectCallback, func() { cn.block.onConnect(sc) })
 
 	return true
 }
@@ -62,29 +144,90 @@ func (cn *controller) serverDisconnected() bool {
 		return false
 	}
 
-	go cn.block.onDisconnect()
+	go cn.safe(OnDisconnectCallback, cn.block.onDisconnect)
+
+	return true
+}
+
+func (cn *controller) configChanged(confNames []string, cf ConfFactory) bool {
+	if cn.block.onConfChange == nil {
+		return false
+	}
+
+	for _, name := range confNames {
+		go cn.safe(OnConfigChangeCallback, func(confName string) func() {
+			return func() { cn.block.onConfChange(confName, cf) }
+		}(name))
+	}
 
 	return true
 }
 
 func (cn *controller) Finish() {
 
-	icn := cn.actBlks[0].controller
+	icn := cn.actBlks.initiator()
 	resp := cn.descriptor.Responsibility
 
 	// This message will be processed by initiator:
-	fm := make(Msg)
-	fm["__name"] = finishedMsg
+	fm := FinishedMsg()
 	fm["__resp"] = resp
 
+	// Timers of finished block are not needed
+	cn.timers.stopAll()
+
 	// Dedicate goroutine for finish of the block
 	go func(fn Finish, bc BlockCommunicator, m Msg, pr *msgProcessor) {
-		fn(false)
+		cn.safe(FinishCallback, func() { fn(false) })
 		if pr != nil {
 			pr.cancel()
+			pr.wait() // in-flight OnMsg
 		}
-		icn.Send(fm) // Send message to initiator about finished block
+		cn.timers.wait() // no sends of timers after finish
+		icn.Send(fm)     // Send message to initiator about finished block
 	}(cn.block.finish, icn, fm, cn.mpr)
 
 	return
 }
+
+// Shallow copy of the message
+func copymsg(msg Msg) Msg {
+	result := make(Msg, len(msg)+1)
+	for k, v := range msg {
+		result[k] = v
+	}
+	return result
+}
+
+func (cn *controller) processMsg(msg Msg) {
+	cn.handleMsg(msg, func() {
+		cn.safe(OnMsgCallback, func() { cn.block.onMsg(msg) })
+	})
+}
+
+// Partition key of the block, panic within key function is reported
+// and message is processed as message with empty key
+func (cn *controller) partitionKey() PartitionKey {
+	key := cn.block.partKey
+	if key == nil {
+		return nil
+	}
+
+	return func(msg Msg) (result string) {
+		cn.safe(PartitionKeyCallback, func() { result = key(msg) })
+		return result
+	}
+}
+
+// Runs callback of controlled block.
+// Recovered panic is reported to initiator.
+func (cn *controller) safe(callback string, fn func()) bool {
+	pe := protect(cn.descriptor, callback, fn)
+
+	if pe == nil {
+		return true
+	}
+
+	cn.actBlks.initiator().Send(blockpanicmsg(cn, pe))
+
+	return false
+}
@@ -0,0 +1,166 @@
+package sputnik
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parsed cron expression: "minute hour day-of-month month day-of-week"
+//
+// Every field supports *, values, ranges (1-5), lists (1,15,30) and steps (*/10, 0-30/5).
+// Day of week: 0-7 (0 and 7 - Sunday).
+// Also supported: @yearly, @monthly, @weekly, @daily, @hourly.
+type cronSchedule struct {
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool
+	dowStar bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+func parseCron(spec string) (*cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expr, exists := cronDescriptors[spec]; exists {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	cs := new(cronSchedule)
+
+	bounds := []struct {
+		dst      *uint64
+		min, max int
+	}{
+		{&cs.minute, 0, 59},
+		{&cs.hour, 0, 23},
+		{&cs.dom, 1, 31},
+		{&cs.month, 1, 12},
+		{&cs.dow, 0, 7},
+	}
+
+	for i, b := range bounds {
+		bits, err := parseCronField(fields[i], b.min, b.max)
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", spec, err)
+		}
+		*b.dst = bits
+	}
+
+	// Sunday is 0 and 7
+	if cs.dow&(1<<7) != 0 {
+		cs.dow |= 1
+	}
+
+	cs.domStar = fields[2] == "*"
+	cs.dowStar = fields[4] == "*"
+
+	return cs, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if rng, st, found := strings.Cut(part, "/"); found {
+			n, err := strconv.Atoi(st)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("wrong step in %q", part)
+			}
+			step = n
+			part = rng
+		}
+
+		from, to := min, max
+
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			lo, hi, _ := strings.Cut(part, "-")
+			var err1, err2 error
+			from, err1 = strconv.Atoi(lo)
+			to, err2 = strconv.Atoi(hi)
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("wrong range %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("wrong value %q", part)
+			}
+			from = n
+			if step == 1 {
+				to = n
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return 0, fmt.Errorf("%q out of range [%d-%d]", part, min, max)
+		}
+
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// Returns the first time after t matching the schedule,
+// zero time if there is no such time within 5 years
+func (cs *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if cs.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !cs.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if cs.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if cs.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// If both day of month and day of week are restricted,
+// day matches either of them
+func (cs *cronSchedule) dayMatches(t time.Time) bool {
+	domOk := cs.dom&(1<<uint(t.Day())) != 0
+	dowOk := cs.dow&(1<<uint(t.Weekday())) != 0
+
+	if cs.domStar || cs.dowStar {
+		return domOk && dowOk
+	}
+	return domOk || dowOk
+}
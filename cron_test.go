@@ -0,0 +1,153 @@
+package sputnik
+
+import (
+	"testing"
+	"time"
+)
+
+func bits(values ...int) uint64 {
+	var result uint64
+	for _, v := range values {
+		result |= 1 << uint(v)
+	}
+	return result
+}
+
+func TestParseCronField(t *testing.T) {
+
+	tests := []struct {
+		field    string
+		min, max int
+		expected uint64
+	}{
+		{"*", 0, 7, bits(0, 1, 2, 3, 4, 5, 6, 7)},
+		{"5", 0, 59, bits(5)},
+		{"1-5", 1, 31, bits(1, 2, 3, 4, 5)},
+		{"1,15,30", 1, 31, bits(1, 15, 30)},
+		{"*/15", 0, 59, bits(0, 15, 30, 45)},
+		{"0-30/10", 0, 59, bits(0, 10, 20, 30)},
+		{"5/20", 0, 59, bits(5, 25, 45)},
+		{"1-3,10-12/2", 1, 12, bits(1, 2, 3, 10, 12)},
+		{"23", 0, 23, bits(23)},
+	}
+
+	for _, tt := range tests {
+		result, err := parseCronField(tt.field, tt.min, tt.max)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.field, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%q: expected %b, got %b", tt.field, tt.expected, result)
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"1,,2 * * * *",
+		"@never",
+	}
+
+	for _, spec := range specs {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+
+	at := func(s string) time.Time {
+		tm, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatalf("wrong time %q", s)
+		}
+		return tm
+	}
+
+	tests := []struct {
+		name     string
+		spec     string
+		from     string
+		expected string
+	}{
+		{"step", "*/15 * * * *", "2024-01-01 10:07", "2024-01-01 10:15"},
+		{"strictly after", "*/15 * * * *", "2024-01-01 10:15", "2024-01-01 10:30"},
+		{"hour rollover", "*/15 * * * *", "2024-01-01 10:50", "2024-01-01 11:00"},
+		{"range and list", "0,30 9-17 * * *", "2024-01-01 17:45", "2024-01-02 09:00"},
+		{"hourly", "@hourly", "2024-01-01 10:00", "2024-01-01 11:00"},
+		{"month rollover", "0 0 1 * *", "2024-01-31 12:00", "2024-02-01 00:00"},
+		{"year rollover", "30 23 31 12 *", "2024-12-31 23:30", "2025-12-31 23:30"},
+		{"short months", "0 0 31 * *", "2024-04-01 00:00", "2024-05-31 00:00"},
+		{"leap year", "0 0 29 2 *", "2023-03-01 00:00", "2024-02-29 00:00"},
+		{"month range", "0 12 * 3-4 *", "2024-04-30 13:00", "2025-03-01 12:00"},
+		{"day of week", "0 0 * * 1", "2024-09-01 00:00", "2024-09-02 00:00"},
+		{"sunday as 0", "0 9 * * 0", "2024-09-02 00:00", "2024-09-08 09:00"},
+		{"sunday as 7", "0 9 * * 7", "2024-09-02 00:00", "2024-09-08 09:00"},
+		{"day of month", "0 0 13 * *", "2024-09-01 00:00", "2024-09-13 00:00"},
+		{"dom or dow", "0 0 13 * 5", "2024-09-01 00:00", "2024-09-06 00:00"},
+		{"dom or dow - dom", "0 0 13 * 5", "2024-09-12 00:00", "2024-09-13 00:00"},
+	}
+
+	for _, tt := range tests {
+		cs, err := parseCron(tt.spec)
+		if err != nil {
+			t.Errorf("%s: parse error %v", tt.name, err)
+			continue
+		}
+		if next := cs.next(at(tt.from)); !next.Equal(at(tt.expected)) {
+			t.Errorf("%s: %q from %s - expected %s, got %s", tt.name, tt.spec, tt.from, tt.expected, next)
+		}
+	}
+
+	// 30 February
+	cs, _ := parseCron("0 0 30 2 *")
+	if next := cs.next(at("2024-01-01 00:00")); !next.IsZero() {
+		t.Errorf("expected zero time, got %s", next)
+	}
+}
+
+func TestTimersStopAll(t *testing.T) {
+
+	bt := newBlockTimers()
+
+	returned := make(chan struct{}, 2)
+	for i := 0; i < 2; i++ {
+		bt.start(func(stop <-chan struct{}) {
+			<-stop
+			returned <- struct{}{}
+		})
+	}
+
+	bt.stopAll()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-returned:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timer was not stopped")
+		}
+	}
+
+	// Timers are not started after stopAll
+	bt.start(func(stop <-chan struct{}) {
+		t.Errorf("timer started after stopAll")
+	})()
+}
@@ -0,0 +1,348 @@
+package sputnik
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDeadLetterName           = "deadletter"
+	DefaultDeadLetterResponsibility = "deadletter"
+
+	// Name of the message with undeliverable message, sent to dead-letter block
+	DeadLetterMsgName = "deadLetter"
+
+	// Default number of dead letters stored in memory
+	DefaultDeadLetterCapacity = 1000
+)
+
+// Descriptor of default dead-letter block.
+// The block is built-in, registration of the factory is not required.
+// Supported options:
+//   - "capacity" - max number of dead letters stored in memory (default 1000)
+//   - "spool"    - path of JSONL file for spooling of dead letters (optional)
+func DeadLetterDescriptor() BlockDescriptor {
+	return BlockDescriptor{Name: DefaultDeadLetterName, Responsibility: DefaultDeadLetterResponsibility}
+}
+
+// Returned by ListDeadLetters if dead letter was not written to the spool
+var ErrDeadLetterSpool = errors.New("dead letter was not spooled")
+
+// Undeliverable message
+type DeadLetter struct {
+	// Responsibility of intended recipient
+	To string `json:"to"`
+	// Responsibility of the sender, set if messages are traced (see WithTraceSink)
+	From   string    `json:"from,omitempty"`
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+	Msg    Msg       `json:"msg,omitempty"`
+}
+
+// Request to dead-letter block (see ListDeadLetters, ReplayDeadLetters)
+const (
+	deadLetterListCmd   = "list"
+	deadLetterReplayCmd = "replay"
+)
+
+// ListDeadLetters returns dead letters stored in memory of dead-letter block.
+// dlbc - communicator of dead-letter block.
+// Error of the last failed write to the spool is returned (ErrDeadLetterSpool) together with letters.
+func ListDeadLetters(ctx context.Context, dlbc BlockCommunicator) ([]DeadLetter, error) {
+	resp, err := dlbc.Ask(ctx, Msg{"__dlcmd": deadLetterListCmd})
+	if err != nil {
+		return nil, err
+	}
+	letters, _ := resp["letters"].([]DeadLetter)
+	if serr, _ := resp["spoolErr"].(error); serr != nil {
+		return letters, fmt.Errorf("%w: %v", ErrDeadLetterSpool, serr)
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetters sends stored dead letters to intended recipients again
+// and removes them from memory. Returns number of delivered messages.
+// Undeliverable messages return to dead-letter block.
+// Letters without message (e.g. Send of nil message) are not replayed and stay in memory.
+// Only letters in memory are replayed, the spool is not used:
+// use ReadDeadLetterSpool for recovery of letters after restart of the process.
+func ReplayDeadLetters(ctx context.Context, dlbc BlockCommunicator) (int, error) {
+	resp, err := dlbc.Ask(ctx, Msg{"__dlcmd": deadLetterReplayCmd})
+	if err != nil {
+		return 0, err
+	}
+	delivered, _ := resp["delivered"].(int)
+	return delivered, nil
+}
+
+// ReadDeadLetterSpool reads dead letters from JSONL spool file
+func ReadDeadLetterSpool(path string) ([]DeadLetter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var result []DeadLetter
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		var dl DeadLetter
+		if err = json.Unmarshal(scanner.Bytes(), &dl); err != nil {
+			return result, fmt.Errorf("%s: %w", path, err)
+		}
+		result = append(result, dl)
+	}
+
+	return result, scanner.Err()
+}
+
+func deadLetterBlockFactory(_ BlockDescriptor, opts BlockOptions) (*Block, error) {
+	dlb := &deadLetters{capacity: DefaultDeadLetterCapacity}
+
+	var dlo struct {
+		Capacity int    `json:"capacity"`
+		Spool    string `json:"spool"`
+	}
+
+	if err := opts.Decode(&dlo); err != nil {
+		return nil, err
+	}
+
+	if dlo.Capacity > 0 {
+		dlb.capacity = dlo.Capacity
+	}
+	dlb.spoolPath = dlo.Spool
+
+	block := NewBlock(
+		WithInit(dlb.init),
+		WithRun(dlb.run),
+		WithFinish(dlb.finish),
+		WithOnMsg(dlb.onMsg))
+	return block, nil
+}
+
+// Default dead-letter block: the last dead letters in memory
+// and optional JSONL spool
+type deadLetters struct {
+	capacity  int
+	spoolPath string
+
+	lock     sync.Mutex
+	letters  []DeadLetter
+	spool    *os.File
+	spoolErr error
+
+	communicator BlockCommunicator
+	done         chan struct{}
+}
+
+func (dlb *deadLetters) init(_ ConfFactory) error {
+	dlb.done = make(chan struct{})
+
+	if dlb.spoolPath == "" {
+		return nil
+	}
+
+	spool, err := os.OpenFile(dlb.spoolPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	dlb.spool = spool
+	return nil
+}
+
+func (dlb *deadLetters) run(self BlockCommunicator) {
+	dlb.lock.Lock()
+	dlb.communicator = self
+	dlb.lock.Unlock()
+
+	<-dlb.done
+}
+
+func (dlb *deadLetters) finish(init bool) {
+	close(dlb.done)
+
+	dlb.lock.Lock()
+	defer dlb.lock.Unlock()
+
+	if dlb.spool != nil {
+		dlb.spool.Close()
+		dlb.spool = nil
+	}
+}
+
+func (dlb *deadLetters) onMsg(msg Msg) {
+	if dl, ok := msg["__letter"].(DeadLetter); ok {
+		dlb.store(dl)
+		return
+	}
+
+	cmd, _ := msg["__dlcmd"].(string)
+
+	switch cmd {
+	case deadLetterListCmd:
+		letters, spoolErr := dlb.list()
+		Reply(msg, Msg{"letters": letters, "spoolErr": spoolErr})
+	case deadLetterReplayCmd:
+		Reply(msg, Msg{"delivered": dlb.replay()})
+	}
+}
+
+// Stores dead letter in memory and spool
+func (dlb *deadLetters) store(dl DeadLetter) {
+	dlb.lock.Lock()
+	defer dlb.lock.Unlock()
+
+	dlb.keep(dl)
+
+	if dlb.spool == nil {
+		return
+	}
+
+	dl.Msg = spoolable(dl.Msg)
+
+	line, err := json.Marshal(dl)
+	if err != nil {
+		// Message contains values without JSON representation
+		dlb.spoolErr = fmt.Errorf("message to %s: %w", dl.To, err)
+		dl.Reason = fmt.Sprintf("%s (message was not spooled: %v)", dl.Reason, err)
+		dl.Msg = nil
+		line, _ = json.Marshal(dl)
+	}
+
+	if _, err = dlb.spool.Write(append(line, '\n')); err != nil {
+		dlb.spoolErr = err
+	}
+}
+
+// Stores dead letter in memory, called under lock
+func (dlb *deadLetters) keep(dl DeadLetter) {
+	if len(dlb.letters) >= dlb.capacity {
+		dlb.letters[0] = DeadLetter{}
+		dlb.letters = dlb.letters[1:]
+	}
+	dlb.letters = append(dlb.letters, dl)
+}
+
+// Copy of the message without house-keeping values, which have no JSON representation,
+// e.g. reply channel of Ask ("__reply")
+func spoolable(msg Msg) Msg {
+	if msg == nil {
+		return nil
+	}
+
+	result := make(Msg, len(msg))
+	for k, v := range msg {
+		if strings.HasPrefix(k, "__") {
+			if _, err := json.Marshal(v); err != nil {
+				continue
+			}
+		}
+		result[k] = v
+	}
+	return result
+}
+
+func (dlb *deadLetters) list() ([]DeadLetter, error) {
+	dlb.lock.Lock()
+	defer dlb.lock.Unlock()
+
+	result := make([]DeadLetter, len(dlb.letters))
+	copy(result, dlb.letters)
+	return result, dlb.spoolErr
+}
+
+func (dlb *deadLetters) replay() int {
+	dlb.lock.Lock()
+	bc := dlb.communicator
+	letters := dlb.letters
+	if bc != nil {
+		dlb.letters = nil
+	}
+	dlb.lock.Unlock()
+
+	if bc == nil {
+		return 0
+	}
+
+	delivered := 0
+	for _, dl := range letters {
+		if dl.Msg == nil {
+			// nothing to deliver
+			dlb.lock.Lock()
+			dlb.keep(dl)
+			dlb.lock.Unlock()
+			continue
+		}
+		rbc, exists := bc.Communicator(dl.To)
+		if !exists {
+			// wait for recipient, the letter is already spooled
+			dlb.lock.Lock()
+			dlb.keep(dl)
+			dlb.lock.Unlock()
+			continue
+		}
+		if rbc.Send(dl.Msg) {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// Message accepted by mailbox of the block, but dropped on overflow
+// or discarded after finish of the block
+func (cn *controller) undelivered(msg Msg, reason error) {
+	from := ""
+	if cn.traced() {
+		from, _ = msg["__from"].(string)
+	}
+	cn.deadLetter(from, msg, reason)
+}
+
+// Sends undeliverable message to dead-letter block.
+// from - responsibility of the sender, empty if unknown (messages are not traced).
+// Dead letters of dead-letter block are lost.
+// Sender does not wait on full mailbox of dead-letter block:
+// dead letter is dropped and reported to TraceSink as not delivered hop.
+func (cn *controller) deadLetter(from string, msg Msg, reason error) {
+	resp := cn.actBlks.deadLetters
+	if resp == "" || resp == cn.descriptor.Responsibility {
+		return
+	}
+
+	abl, exists := cn.actBlks.getABl(resp)
+	if !exists {
+		return
+	}
+
+	dlcn := abl.currentController()
+	if dlcn == nil {
+		return
+	}
+
+	dl := DeadLetter{
+		To:     cn.descriptor.Responsibility,
+		From:   from,
+		Reason: reason.Error(),
+		Time:   time.Now(),
+		Msg:    msg,
+	}
+
+	dlm := make(Msg)
+	dlm["__name"] = DeadLetterMsgName
+	dlm["__letter"] = dl
+
+	if err := dlcn.mpr.offer(dlm); err != nil && msg != nil {
+		dlcn.trace(msg, false)
+	}
+}
@@ -0,0 +1,70 @@
+package sputnik
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Sorts descriptors in topological order according to DependsOn:
+// block is placed after all blocks it depends on.
+// Positional order of descriptors is used as tie-breaker.
+func sortByDependencies(dscrs []BlockDescriptor) ([]BlockDescriptor, error) {
+
+	index := make(map[string]int, len(dscrs))
+	for i, bd := range dscrs {
+		index[bd.Responsibility] = i
+	}
+
+	indegree := make([]int, len(dscrs))
+	dependants := make([][]int, len(dscrs))
+
+	for i, bd := range dscrs {
+		for _, dep := range bd.DependsOn {
+			if dep == InitiatorResponsibility {
+				continue // always the first
+			}
+			j, exists := index[dep]
+			if !exists {
+				return nil, fmt.Errorf("block [%s,%s] depends on missing block %s", bd.Name, bd.Responsibility, dep)
+			}
+			indegree[i]++
+			dependants[j] = append(dependants[j], i)
+		}
+	}
+
+	ready := make([]int, 0)
+	for i := range dscrs {
+		if indegree[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	result := make([]BlockDescriptor, 0, len(dscrs))
+
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		next := ready[0]
+		ready = ready[1:]
+
+		result = append(result, dscrs[next])
+
+		for _, d := range dependants[next] {
+			indegree[d]--
+			if indegree[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	if len(result) < len(dscrs) {
+		cycle := make([]string, 0)
+		for i, bd := range dscrs {
+			if indegree[i] > 0 {
+				cycle = append(cycle, bd.Responsibility)
+			}
+		}
+		return nil, fmt.Errorf("dependency cycle between blocks %v", cycle)
+	}
+
+	return result, nil
+}
@@ -9,6 +9,8 @@ import (
 type dumbBlock struct {
 	// Block communicator
 	communicator sputnik.BlockCommunicator
+	// Closed after Start, communicator is valid
+	ready chan struct{}
 	// Main queue of test
 	q *kissngoqueue.Queue[sputnik.Msg]
 	// Used for synchronization
@@ -16,23 +18,43 @@ type dumbBlock struct {
 	// This pattern may be used in real application
 	stop chan struct{}
 	done chan struct{}
+	// If set, Finish waits till close of hang
+	// Used for simulation of hanging block
+	hang chan struct{}
 }
 
 // dumbBlock support all callbacks of Block:
 //
 // Init
-func (dmb *dumbBlock) init(_ sputnik.ConfFactory) error {
+func (dmb *dumbBlock) init(cf sputnik.ConfFactory) error {
+	var conf struct{}
+	if err := cf("dumb", &conf); err != nil {
+		return err
+	}
 	dmb.stop = make(chan struct{}, 1)
+	dmb.done = make(chan struct{})
 	return nil
 }
 
+// Start:
+func (dmb *dumbBlock) start(bc sputnik.BlockCommunicator) {
+	// Save for further communication with blocks.
+	// Readiness of the block is reported after return from Start,
+	// so communicator may be used by test after WaitReady
+	dmb.communicator = bc
+	close(dmb.ready)
+	return
+}
+
+// Communicator of the block, waits start of the block
+func (dmb *dumbBlock) bc() sputnik.BlockCommunicator {
+	<-dmb.ready
+	return dmb.communicator
+}
+
 // Run:
 func (dmb *dumbBlock) run(bc sputnik.BlockCommunicator) {
 
-	// Save for further communication with blocks
-	dmb.communicator = bc
-
-	dmb.done = make(chan struct{})
 	defer close(dmb.done)
 
 	// select isn't required for one channel
@@ -59,6 +81,10 @@ func (dmb *dumbBlock) finish(init bool) {
 	case <-dmb.done: // Wait finish of Run
 		break
 	}
+
+	if dmb.hang != nil {
+		<-dmb.hang
+	}
 	return
 }
 
@@ -80,8 +106,66 @@ func (dmb *dumbBlock) serverDisconnected() {
 	return
 }
 
+// OnConfigChange:
+func (dmb *dumbBlock) configChanged(confName string, cf sputnik.ConfFactory) {
+	//Inform test about event
+	m := make(sputnik.Msg)
+	m["__name"] = "configChanged"
+	m["conf"] = confName
+	dmb.send(m)
+	return
+}
+
 // OnMsg:
 func (dmb *dumbBlock) eventReceived(msg sputnik.Msg) {
+	if _, exists := msg["panic"]; exists {
+		panic("requested by test")
+	}
+
+	// Processing of the message started
+	if entered, exists := msg["entered"].(chan struct{}); exists {
+		close(entered)
+	}
+
+	// Simulation of slow processing
+	if gate, exists := msg["wait"].(chan struct{}); exists {
+		<-gate
+	}
+
+	// Forward message to another block
+	if to, exists := msg["forward"].(string); exists {
+		fm := make(sputnik.Msg)
+		for k, v := range msg {
+			fm[k] = v
+		}
+		delete(fm, "forward")
+
+		// Send on another goroutine continues the chain via CausedBy
+		if _, exists := msg["async"]; exists {
+			delete(fm, "async")
+			bc := sputnik.CausedBy(dmb.communicator, msg)
+			go func() {
+				if to, ok := bc.Communicator(to); ok {
+					to.Send(fm)
+				}
+			}()
+			return
+		}
+
+		if bc, ok := dmb.communicator.Communicator(to); ok {
+			bc.Send(fm)
+		}
+		return
+	}
+
+	// Request sent by Ask
+	if sputnik.IsRequest(msg) {
+		if q, exists := msg["ask"]; exists {
+			sputnik.Reply(msg, sputnik.Msg{"echo": q})
+		}
+		return
+	}
+
 	//Inform test about event
 	dmb.send(msg)
 	return
@@ -2,31 +2,32 @@ package sputnik
 
 import (
 	"errors"
+	"sync/atomic"
 )
 
 var _ ServerConnector = &DummyConnector{}
 
 // Connector's plugin used for debugging/testing
 type DummyConnector struct {
-	connected bool
+	connected atomic.Bool
 }
 
 func (c *DummyConnector) Connect(cf ConfFactory) (conn ServerConnection, err error) {
-	if !c.connected {
+	if !c.connected.Load() {
 		return nil, errors.New("connection failed")
 	}
 	return "connected", nil
 }
 
 func (c *DummyConnector) IsConnected() bool {
-	return c.connected
+	return c.connected.Load()
 }
 
 func (c *DummyConnector) Disconnect() {
-	c.connected = false
+	c.connected.Store(false)
 }
 
 // Allows to simulate state of the connection
 func (c *DummyConnector) SetState(connected bool) {
-	c.connected = connected
+	c.connected.Store(connected)
 }
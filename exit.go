@@ -0,0 +1,159 @@
+package sputnik
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ExitReason describes why sputnik finished the flight
+type ExitReason int
+
+const (
+	// Finish requested by FinishMsg
+	ExitFinishRequest ExitReason = iota
+
+	// Signal received by finisher
+	ExitSignal
+
+	// ShootDown called
+	ExitShootDown
+
+	// Flight context cancelled
+	ExitContext
+
+	// Block requested finish via FatalMsg
+	ExitFatal
+
+	// Panic within block callback (PanicShutdown policy)
+	ExitPanic
+
+	// Supervisor exceeded restarts limit of the block
+	ExitRestartsExceeded
+)
+
+func (er ExitReason) String() string {
+	switch er {
+	case ExitSignal:
+		return "signal"
+	case ExitShootDown:
+		return "shootdown"
+	case ExitContext:
+		return "context cancelled"
+	case ExitFatal:
+		return "fatal error"
+	case ExitPanic:
+		return "panic"
+	case ExitRestartsExceeded:
+		return "restarts exceeded"
+	}
+	return "finish request"
+}
+
+// ExitError is returned by Launch for abnormal end of the flight:
+// fatal error or panic of the block, exceeded restarts, abandoned blocks.
+// Clean finish (signal, ShootDown, FinishMsg, cancel of the context) - Launch returns nil,
+// ExitError with Code 0 is available via Flight.Exit.
+type ExitError struct {
+	Reason ExitReason
+
+	// Name of the signal for ExitSignal
+	Signal string
+
+	// Cause of the finish for ExitFatal, ExitPanic, ExitRestartsExceeded
+	Cause error
+
+	// Errors of blocks collected during the flight:
+	// panics, failed restarts, abandoned blocks
+	BlockErrors []error
+
+	// Suggested exit code of the process
+	Code int
+}
+
+func (ee *ExitError) Error() string {
+	result := "sputnik exit: " + ee.Reason.String()
+
+	if ee.Signal != "" {
+		result += " " + ee.Signal
+	}
+
+	if ee.Cause != nil {
+		result += ": " + ee.Cause.Error()
+	}
+
+	if len(ee.BlockErrors) > 0 {
+		result += fmt.Sprintf("; block errors: %v", ee.BlockErrors)
+	}
+
+	return result
+}
+
+func (ee *ExitError) Unwrap() error {
+	return ee.Cause
+}
+
+// ExitCode returns suggested exit code of the process for error returned by Launch
+//   - nil - 0
+//   - *ExitError - Code
+//   - other - 1
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var ee *ExitError
+	if errors.As(err, &ee) {
+		return ee.Code
+	}
+
+	return 1
+}
+
+// FatalMsg creates message for initiator.
+// Block sends it in order to finish the process because of unrecoverable error:
+//
+//	ibc, _ := bc.Communicator(sputnik.InitiatorResponsibility)
+//	ibc.Send(sputnik.FatalMsg(err))
+func FatalMsg(err error) Msg {
+	msg := FinishMsg()
+	msg["__reason"] = ExitFatal
+	msg["__err"] = err
+	return msg
+}
+
+func finishmsg(reason ExitReason) Msg {
+	msg := FinishMsg()
+	msg["__reason"] = reason
+	return msg
+}
+
+func signalmsg(signal string) Msg {
+	msg := finishmsg(ExitSignal)
+	msg["__signal"] = signal
+	return msg
+}
+
+// Describes the end of the flight, Code 0 - clean finish
+func (inr *initiator) exitError() *ExitError {
+	ee := &ExitError{
+		Reason:      inr.reason,
+		Signal:      inr.signal,
+		Cause:       inr.cause,
+		BlockErrors: inr.blkErrors,
+	}
+
+	switch ee.Reason {
+	case ExitFatal, ExitPanic, ExitRestartsExceeded:
+		ee.Code = 1
+	}
+
+	if len(inr.abandoned) > 0 {
+		ee.Code = 1
+	}
+
+	return ee
+}
+
+func (inr *initiator) addBlockError(err error) {
+	inr.blkErrors = append(inr.blkErrors, err)
+}
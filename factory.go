@@ -1,7 +1,10 @@
 package sputnik
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
 )
 
 // BlockFactory should be provided for every block in the process
@@ -9,6 +12,215 @@ type BlockFactory func() *Block
 
 type BlockFactories map[string]BlockFactory
 
+// ParamBlockFactory creates block for specific descriptor.
+// Use it instead of BlockFactory if creation of block depends on
+// responsibility or on per-instance options, e.g.
+//
+//	func receiverFactory(bd sputnik.BlockDescriptor, opts sputnik.BlockOptions) (*sputnik.Block, error) {
+//		var ro receiverOptions
+//		if err := opts.Decode(&ro); err != nil {
+//			return nil, err
+//		}
+//		return newReceiver(bd.Responsibility, ro), nil
+//	}
+//
+// Returned error is reported by sputnik as reason of failed creation.
+type ParamBlockFactory func(bd BlockDescriptor, opts BlockOptions) (*Block, error)
+
+type ParamBlockFactories map[string]ParamBlockFactory
+
+// Per-instance options of the block, "options" object of blocks.json entry
+type BlockOptions map[string]any
+
+// Decode copies options to result (pointer to struct|map) using json tags of result
+func (opts BlockOptions) Decode(result any) error {
+	if len(opts) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, result)
+}
+
+// Registry of block factories.
+// Every sputnik uses own registry (see WithRegistry), by default -
+// global registry of the process filled via RegisterBlockFactory.
+// Use separate registries for differently wired sputniks within
+// the same process, e.g. in tests.
+type Registry struct {
+	lock   sync.RWMutex
+	facts  BlockFactories
+	params ParamBlockFactories
+	// Names of typed messages (see RegisterMsgType)
+	types *msgTypes
+}
+
+func NewRegistry() *Registry {
+	return &Registry{facts: make(BlockFactories), params: make(ParamBlockFactories), types: newMsgTypes()}
+}
+
+// Creates registry with copy of factories
+func NewRegistryFrom(facts BlockFactories) *Registry {
+	r := NewRegistry()
+	for name, bf := range facts {
+		r.facts[name] = bf
+	}
+	return r
+}
+
+// Global registry of the process
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Registers factory, returns error for duplicated name
+func (r *Registry) Register(name string, bf BlockFactory) error {
+	if err := validateFactory(name, bf); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, exists := r.params[name]; exists {
+		return fmt.Errorf("RegisterBlockFactory: %s already registered", name)
+	}
+	return RegisterBlockFactoryInner(name, bf, r.facts)
+}
+
+// Registers parameterized factory, returns error for duplicated name
+func (r *Registry) RegisterParam(name string, pf ParamBlockFactory) error {
+	if err := validateFactory(name, pf); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.exists(name) {
+		return fmt.Errorf("RegisterBlockFactory: %s already registered", name)
+	}
+	r.params[name] = pf
+	return nil
+}
+
+// Registers factory, replaces already registered factory with the same name
+func (r *Registry) Override(name string, bf BlockFactory) error {
+	if err := validateFactory(name, bf); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.params, name)
+	r.facts[name] = bf
+	return nil
+}
+
+// Registers parameterized factory, replaces already registered factory with the same name
+func (r *Registry) OverrideParam(name string, pf ParamBlockFactory) error {
+	if err := validateFactory(name, pf); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.facts, name)
+	r.params[name] = pf
+	return nil
+}
+
+// Removes factory, returns false if factory was not registered
+func (r *Registry) Unregister(name string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	exists := r.exists(name)
+	delete(r.facts, name)
+	delete(r.params, name)
+	return exists
+}
+
+// Sorted names of registered factories
+func (r *Registry) List() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	names := make([]string, 0, len(r.facts)+len(r.params))
+	for name := range r.facts {
+		names = append(names, name)
+	}
+	for name := range r.params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Registry) Factory(name string) (BlockFactory, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	fct, exists := r.facts[name]
+
+	if !exists {
+		return nil, fmt.Errorf("factory for %s does not exist", name)
+	}
+	return fct, nil
+}
+
+func (r *Registry) ParamFactory(name string) (ParamBlockFactory, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	pf, exists := r.params[name]
+
+	if !exists {
+		return nil, fmt.Errorf("factory for %s does not exist", name)
+	}
+	return pf, nil
+}
+
+// Copy of registered factories (without parameterized)
+func (r *Registry) Factories() BlockFactories {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	result := make(BlockFactories, len(r.facts))
+	for name, bf := range r.facts {
+		result[name] = bf
+	}
+	return result
+}
+
+// Creates new registry with the same factories.
+// Use it for override of factory (e.g. finisher) for one sputnik only.
+func (r *Registry) Clone() *Registry {
+	result := NewRegistryFrom(r.Factories())
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for name, pf := range r.params {
+		result.params[name] = pf
+	}
+	result.types = r.types.clone()
+	return result
+}
+
+func (r *Registry) exists(name string) bool {
+	_, exists := r.facts[name]
+	if !exists {
+		_, exists = r.params[name]
+	}
+	return exists
+}
+
 // BlockFactory registered in the process via RegisterBlockFactory
 // Please pay attention that panic called for any error during registration.
 //
@@ -18,29 +230,25 @@ type BlockFactories map[string]BlockFactory
 //			sputnik.RegisterBlockFactory("syslogPublisher", slpbFactory)
 //		}
 func RegisterBlockFactory(name string, bf BlockFactory) {
-	if name == "" {
-		panic("RegisterBlockFactory: empty block name")
-	}
-	if bf == nil {
-		panic(fmt.Errorf("RegisterBlockFactory: nil block factory for %s", name))
-	}
-
-	if blockFactories == nil {
-		blockFactories = make(map[string]BlockFactory)
+	if err := DefaultRegistry().Register(name, bf); err != nil {
+		panic(err)
 	}
+}
 
-	err := RegisterBlockFactoryInner(name, bf, blockFactories)
-
-	if err != nil {
+// ParamBlockFactory registered in the process via RegisterParamBlockFactory
+// Same rules as for RegisterBlockFactory.
+func RegisterParamBlockFactory(name string, pf ParamBlockFactory) {
+	if err := DefaultRegistry().RegisterParam(name, pf); err != nil {
 		panic(err)
 	}
 }
 
+// Factories of global registry, the map itself (not a copy):
+// factories added to the map are used by the global registry.
+// The map is not protected by lock of the registry,
+// use RegisterBlockFactory for registration after start of the process.
 func DefaultFactories() BlockFactories {
-	if blockFactories == nil {
-		blockFactories = make(map[string]BlockFactory)
-	}
-	return blockFactories
+	return DefaultRegistry().facts
 }
 
 func RegisterBlockFactoryInner(name string, bf BlockFactory, facts BlockFactories) error {
@@ -52,37 +260,39 @@ func RegisterBlockFactoryInner(name string, bf BlockFactory, facts BlockFactorie
 	return nil
 }
 
+// Factory from global registry
 func Factory(name string) (BlockFactory, error) {
-	bfs := DefaultFactories()
-
-	fct, exists := bfs[name]
+	return DefaultRegistry().Factory(name)
+}
 
-	if !exists {
-		return nil, fmt.Errorf("factory for %s does not exist", name)
+func validateFactory[F BlockFactory | ParamBlockFactory](name string, f F) error {
+	if name == "" {
+		return fmt.Errorf("RegisterBlockFactory: empty block name")
 	}
-	return fct, nil
+	if f == nil {
+		return fmt.Errorf("RegisterBlockFactory: nil block factory for %s", name)
+	}
+	return nil
 }
 
-func (bfs BlockFactories) createByName(name string) (blk *Block, exist bool) {
-	fct, ok := bfs[name]
-
-	if !ok {
-		return nil, false
+func (r *Registry) createByDescr(bd *BlockDescriptor) (blk *Block, err error) {
+	if pf, perr := r.ParamFactory(bd.Name); perr == nil {
+		blk, err = pf(*bd, bd.Options)
+	} else if fct, ferr := r.Factory(bd.Name); ferr == nil {
+		blk = fct()
+	} else {
+		err = ferr
 	}
 
-	bl := fct()
-
-	return bl, true
-}
-
-func (bfs BlockFactories) createByDescr(bd *BlockDescriptor) (blk *Block, err error) {
-	blk, ok := bfs.createByName(bd.Name)
+	if err == nil && blk == nil {
+		err = fmt.Errorf("factory returned nil block")
+	}
 
-	if !ok {
-		return nil, fmt.Errorf("Creation of block [name: %s resp: %s] failed", bd.Name, bd.Responsibility)
+	if err != nil {
+		return nil, fmt.Errorf("Creation of block [name: %s resp: %s] failed: %w", bd.Name, bd.Responsibility, err)
 	}
 
 	return blk, nil
 }
 
-var blockFactories = make(map[string]BlockFactory)
+var defaultRegistry = NewRegistry()
@@ -7,7 +7,7 @@ import (
 )
 
 func FinisherDescriptor() BlockDescriptor {
-	return BlockDescriptor{DefaultFinisherName, DefaultFinisherResponsibility}
+	return BlockDescriptor{Name: DefaultFinisherName, Responsibility: DefaultFinisherResponsibility}
 }
 
 func init() {
@@ -18,6 +18,7 @@ func finisherBlockFactory() *Block {
 	finisher := new(finisher)
 	block := NewBlock(
 		WithInit(finisher.init),
+		WithStart(finisher.start),
 		WithRun(finisher.run),
 		WithFinish(finisher.finish),
 		WithOnMsg(finisher.debug))
@@ -32,32 +33,40 @@ type finisher struct {
 }
 
 func (bl *finisher) init(_ ConfFactory) error {
+	bl.done = make(chan struct{})
+	bl.term = make(chan os.Signal, 3)
 	return nil
 }
 
+// Signals are caught before readiness of the process (see WaitReady)
+func (bl *finisher) start(_ BlockCommunicator) {
+	signal.Notify(bl.term, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
+}
+
 func (bl *finisher) run(self BlockCommunicator) {
 	bl.communicator = self
 
-	bl.done = make(chan struct{})
-
-	bl.term = make(chan os.Signal, 3)
-	signal.Notify(bl.term, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
-
 	defer func() {
-		signal.Reset(syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+		signal.Reset(syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
 		close(bl.term)
 	}()
 
-	select {
-	case <-bl.done:
-		return
-
-	case <-bl.term:
-		ibc, _ := bl.communicator.Communicator(InitiatorResponsibility)
-		ibc.Send(FinishMsg())
+	ibc, _ := bl.communicator.Communicator(InitiatorResponsibility)
+
+	for {
+		select {
+		case <-bl.done:
+			return
+
+		case sig := <-bl.term:
+			if sig == syscall.SIGHUP { // Reload of configuration
+				ibc.Send(ConfigChangedMsg(""))
+				continue
+			}
+			ibc.Send(signalmsg(sig.String()))
+			return
+		}
 	}
-
-	return
 }
 
 func (bl *finisher) finish(init bool) {
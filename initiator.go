@@ -1,16 +1,18 @@
 package sputnik
 
 import (
+	"context"
+	"fmt"
 	"sync"
-
-	"github.com/g41797/kissngoqueue"
+	"time"
 )
 
 type initiator struct {
 	sync.Mutex
 	sputnik        Sputnik
-	actBlks        activeBlocks
-	q              *kissngoqueue.Queue[Msg]
+	ctx            context.Context
+	actBlks        *blocksSet
+	q              *mailbox
 	runStarted     bool
 	abortStarted   bool
 	finishStarted  bool
@@ -18,6 +20,24 @@ type initiator struct {
 	expectFinished int
 	done           chan struct{}
 	connector      *controller
+	finished       map[string]bool
+	abandoned      []string
+	timers         map[string]*time.Timer
+	finishing      map[string]bool
+	restarting     map[string]bool
+	conn           ServerConnection
+	connected      bool
+	reason         ExitReason
+	signal         string
+	cause          error
+	blkErrors      []error
+	exit           *ExitError
+	removals       map[string]chan error
+	adding         map[string]bool
+	stopWatcher    func()
+	ready          map[string]bool
+	readyReported  bool
+	allReady       chan struct{}
 }
 
 // Factory of initiator:
@@ -37,37 +57,71 @@ func (inr *initiator) init(_ ConfFactory) error {
 		return err
 	}
 
-	ibs := make(activeBlocks, 0)
+	var ibs activeBlocks
 
-	for _, abl := range appBlks {
-		err = abl.init(inr.sputnik.cnfFact)
-		if err != nil {
-			break
-		}
-		ibs = append(ibs, abl)
+	if inr.sputnik.workers > 1 {
+		ibs, err = inr.initParallel(appBlks)
+	} else {
+		ibs, err = inr.initSequential(appBlks)
 	}
 
 	if err != nil {
 		for i := len(ibs) - 1; i >= 0; i-- {
-			ibs[i].finish()
+			inr.finishInitialized(ibs[i])
 		}
 
 		return err
 	}
 
-	inr.actBlks = make(activeBlocks, 0)
-	inr.actBlks = append(inr.actBlks, inr.activeinitiator())
-	inr.actBlks = append(inr.actBlks, ibs...)
+	abls := make(activeBlocks, 0)
+	abls = append(abls, inr.activeinitiator())
+	abls = append(abls, appBlks...)
+	inr.actBlks = newBlocksSet(abls, InitiatorResponsibility, inr.sputnik.fbd.Responsibility, DefaultConnectorResponsibility)
+	inr.actBlks.sink = inr.sputnik.ts
+	inr.actBlks.reg = inr.sputnik.reg
+	if inr.sputnik.dlbd != nil {
+		inr.actBlks.deadLetters = inr.sputnik.dlbd.Responsibility
+		inr.actBlks.infra[inr.actBlks.deadLetters] = true
+	}
 
 	inr.addControllers()
 
-	inr.q = kissngoqueue.NewQueue[Msg]()
+	inr.q = newMailbox(0, OverflowBlock)
+	inr.q.discard = func(m Msg, _ error) { inr.discardMsg(m) }
+
+	inr.done = make(chan struct{})
+	inr.actBlks.stopped = inr.done
+	inr.finished = make(map[string]bool)
+	inr.timers = make(map[string]*time.Timer)
+	inr.finishing = make(map[string]bool)
+	inr.restarting = make(map[string]bool)
+	inr.removals = make(map[string]chan error)
+	inr.adding = make(map[string]bool)
+	inr.ready = make(map[string]bool)
+	inr.allReady = make(chan struct{})
 
 	inr.setupConnector()
 
 	return nil
 }
 
+// Returns initialized blocks in order of initialization
+func (inr *initiator) initSequential(appBlks activeBlocks) (activeBlocks, error) {
+	ibs := make(activeBlocks, 0)
+
+	for _, abl := range appBlks {
+		if err := inr.ctx.Err(); err != nil {
+			return ibs, err
+		}
+		if err := inr.initBlock(abl); err != nil {
+			return ibs, err
+		}
+		ibs = append(ibs, abl)
+	}
+
+	return ibs, nil
+}
+
 func (inr *initiator) setupConnector() {
 	connector := inr.sputnik.cnt
 	to := inr.sputnik.to
@@ -94,16 +148,17 @@ func (inr *initiator) setupConnector() {
 
 func (inr *initiator) run(_ BlockCommunicator) {
 
-	inr.done = make(chan struct{})
 	defer close(inr.done)
 
 	if !inr.activate() {
 		return
 	}
 
+	inr.startConfigWatcher()
+
 	// Main loop
 	for {
-		nm, ok := inr.q.Get()
+		nm, ok := inr.q.get()
 		if !ok { //all blocks finished
 			break
 		}
@@ -125,59 +180,108 @@ func (inr *initiator) activate() bool {
 	}
 
 	// Start active blacks on own goroutines
-	for _, abl := range inr.actBlks[1:] {
-		go func(fr Run, bc BlockCommunicator) {
-			fr(bc)
-		}(abl.block.run, abl.controller)
+	for _, abl := range inr.actBlks.appBlocks() {
+		inr.runBlock(abl)
 	}
 
 	inr.runStarted = true
 	return true
 }
 
+// Starts Run of the block on own goroutine.
+// Initiator is informed about return from Run.
+func (inr *initiator) runBlock(abl *activeBlock) {
+	delete(inr.ready, abl.descriptor.Responsibility)
+
+	go func(bl *Block, cn *controller) {
+		if bl.start != nil {
+			cn.safe(StartCallback, func() { bl.start(cn) })
+		}
+		if !bl.manualReady {
+			cn.Ready()
+		}
+		inr.sputnik.observe(RunStarted, cn.descriptor, nil)
+		cn.safe(RunCallback, func() { bl.run(cn) })
+		inr.sputnik.observe(RunReturned, cn.descriptor, nil)
+		cn.actBlks.initiator().Send(runreturnedmsg(cn))
+	}(abl.block, abl.controller)
+}
+
 func (inr *initiator) finish(init bool) {
 	if init {
 		return
 	}
 
-	inr.q.PutMT(FinishMsg())
+	inr.put(FinishMsg())
 	return
 }
 
 func (inr *initiator) msgReceived(msg Msg) {
-	inr.q.PutMT(msg)
+	inr.put(msg)
 	return
 }
 
+// Puts message to the main loop according to priority
+func (inr *initiator) put(msg Msg) {
+	inr.q.put(context.Background(), msg, msgPriority(msg))
+}
+
 func (inr *initiator) onServerConnected(connection ServerConnection) {
-	for _, abl := range inr.actBlks[1:] {
-		abl.controller.ServerConnected(connection)
+	inr.conn = connection
+	inr.connected = true
+	for _, abl := range inr.actBlks.appBlocks() {
+		inr.connectBlock(abl, connection)
 	}
+	inr.checkReadiness()
 	return
 }
 
 func (inr *initiator) onserverDisconnected() {
-	for _, abl := range inr.actBlks[1:] {
-		abl.controller.serverDisconnected()
+	inr.conn = nil
+	inr.connected = false
+	for _, abl := range inr.actBlks.appBlocks() {
+		inr.disconnectBlock(abl)
 	}
 	return
 }
 
 func (inr *initiator) runInternal() (err error) {
 
+	go inr.watchContext()
+
 	inr.run(nil)
 
-	return nil
+	ee := inr.exitError()
+
+	inr.Lock()
+	inr.exit = ee
+	inr.Unlock()
+
+	if ee.Code != 0 {
+		err = ee
+	}
+
+	inr.sputnik.observe(ProcessExit, BlockDescriptor{}, err)
+
+	return err
+}
+
+// Cancel of the flight context is processed like FinishMsg
+func (inr *initiator) watchContext() {
+	select {
+	case <-inr.ctx.Done():
+		inr.put(finishmsg(ExitContext))
+	case <-inr.done:
+	}
 }
 
-// TODO Add timeout for abort/ShootDown
 func (inr *initiator) abort() {
 
 	if inr.finishBeforeLaunch() {
 		return
 	}
 
-	inr.finish(false)
+	inr.put(finishmsg(ExitShootDown))
 
 	select {
 	case <-inr.done:
@@ -185,6 +289,40 @@ func (inr *initiator) abort() {
 	}
 }
 
+func (inr *initiator) abortWithTimeout(to time.Duration) error {
+
+	if inr.finishBeforeLaunch() {
+		return nil
+	}
+
+	inr.put(finishmsg(ExitShootDown))
+
+	timer := time.NewTimer(to)
+	defer timer.Stop()
+
+	select {
+	case <-inr.done:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("ShootDown timeout %v: blocks %v were not finished", to, inr.unfinished())
+	}
+}
+
+// Responsibilities of blocks without report about finish
+func (inr *initiator) unfinished() []string {
+	inr.Lock()
+	defer inr.Unlock()
+
+	result := make([]string, 0)
+	for _, abl := range inr.actBlks.appBlocks() {
+		resp := abl.descriptor.Responsibility
+		if !inr.finished[resp] {
+			result = append(result, resp)
+		}
+	}
+	return result
+}
+
 func (inr *initiator) finishBeforeLaunch() bool {
 	inr.Lock()
 	defer inr.Unlock()
@@ -193,22 +331,23 @@ func (inr *initiator) finishBeforeLaunch() bool {
 		return false
 	}
 
-	for i := len(inr.actBlks) - 1; i > 0; i-- {
-		inr.actBlks[i].finish()
+	abls := inr.actBlks.blocks()
+	for i := len(abls) - 1; i > 0; i-- {
+		inr.finishInitialized(abls[i])
 	}
 
 	inr.abortStarted = true
+	inr.reason = ExitShootDown
 	return true
 }
 
 func (inr *initiator) activeinitiator() *activeBlock {
-	ibl := newActiveBlock(
-		BlockDescriptor{InitiatorResponsibility, InitiatorResponsibility}, inr.factory())
-	return &ibl
+	return newActiveBlock(
+		BlockDescriptor{Name: InitiatorResponsibility, Responsibility: InitiatorResponsibility}, inr.factory())
 }
 
 func (inr *initiator) addControllers() {
-	for _, abl := range inr.actBlks {
+	for _, abl := range inr.actBlks.blocks() {
 		attachController(abl.descriptor.Responsibility, inr.actBlks)
 	}
 	return
@@ -230,9 +369,29 @@ func (inr *initiator) processMsg(m Msg) {
 
 	switch name {
 	case finishMsg:
-		inr.processFinish()
+		inr.processFinishMsg(m)
 	case finishedMsg:
-		inr.processFinished()
+		inr.processFinished(m)
+	case abandonedMsg:
+		inr.processAbandoned(m)
+	case blockPanicMsg:
+		inr.processPanic(m)
+	case runReturnedMsg:
+		inr.processRunReturned(m)
+	case restartMsg:
+		inr.processRestart(m)
+	case blockRestartedMsg:
+		inr.processBlockRestarted(m)
+	case addBlockMsg:
+		inr.processAddBlock(m)
+	case blockAddedMsg:
+		inr.processBlockAdded(m)
+	case removeBlockMsg:
+		inr.processRemoveBlock(m)
+	case configChangedMsg:
+		inr.processConfigChanged(m)
+	case blockReadyMsg:
+		inr.processBlockReady(m)
 	case serverConnectedMsg:
 		inr.onServerConnected(m["__conn"])
 	case serverDisconnectedMsg:
@@ -242,34 +401,290 @@ func (inr *initiator) processMsg(m Msg) {
 	return
 }
 
+func (inr *initiator) processFinishMsg(m Msg) {
+	reason, _ := m["__reason"].(ExitReason)
+	signal, _ := m["__signal"].(string)
+	cause, _ := m["__err"].(error)
+
+	inr.shutdown(reason, signal, cause)
+}
+
+// Starts shutdown of the process, the first reason wins
+func (inr *initiator) shutdown(reason ExitReason, signal string, cause error) {
+	if inr.finishStarted {
+		return
+	}
+
+	inr.reason = reason
+	inr.signal = signal
+	inr.cause = cause
+
+	inr.processFinish()
+}
+
 func (inr *initiator) processFinish() {
 	if inr.finishStarted {
 		return
 	}
 	inr.finishStarted = true
 
-	for i := len(inr.actBlks) - 1; i > 0; i-- {
-		contr := inr.actBlks[i].controller
-		if contr != inr.connector {
-			contr.Finish()
-			inr.expectFinished += 1
+	inr.stopConfigWatcher()
+
+	abls := inr.actBlks.blocks()
+	for i := len(abls) - 1; i > 0; i-- {
+		abl := abls[i]
+		if abl.controller == inr.connector {
+			continue
+		}
+		resp := abl.descriptor.Responsibility
+		if inr.isFinished(resp) || inr.isAbandoned(resp) {
+			continue
 		}
+		inr.expectFinished += 1
 	}
 
 	inr.finishedBlks = 0
+
+	inr.finishReleased()
+
+	if inr.expectFinished == 0 {
+		inr.expectFinished = 1
+		inr.nextFinishStage()
+	}
+}
+
+// Starts finish of blocks without unfinished dependants (see DependsOn):
+// block is finished after all blocks depending on it.
+// Independent blocks are finished concurrently.
+func (inr *initiator) finishReleased() {
+	abls := inr.actBlks.blocks()
+	for i := len(abls) - 1; i > 0; i-- {
+		abl := abls[i]
+		if abl.controller == inr.connector || inr.isFinishing(abl) {
+			continue
+		}
+		resp := abl.descriptor.Responsibility
+		if inr.isFinished(resp) || inr.isAbandoned(resp) {
+			continue
+		}
+		if inr.hasUnfinishedDependants(resp, abls) {
+			continue
+		}
+		inr.finishBlock(abl)
+	}
+}
+
+func (inr *initiator) hasUnfinishedDependants(resp string, abls activeBlocks) bool {
+	for _, abl := range abls[1:] {
+		dresp := abl.descriptor.Responsibility
+		if dresp == DefaultConnectorResponsibility { // finished at the last stage
+			continue
+		}
+		if inr.isFinished(dresp) || inr.isAbandoned(dresp) {
+			continue
+		}
+		for _, dep := range abl.descriptor.DependsOn {
+			if dep == resp {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-func (inr *initiator) processFinished() {
+func (inr *initiator) isFinished(resp string) bool {
+	inr.Lock()
+	defer inr.Unlock()
+	return inr.finished[resp]
+}
+
+func (inr *initiator) isFinishing(abl *activeBlock) bool {
+	return inr.finishing[abl.descriptor.Responsibility]
+}
+
+// Starts finish of the block.
+// If the block does not report about finish till deadline,
+// initiator receives abandonedMsg.
+func (inr *initiator) finishBlock(abl *activeBlock) {
+	inr.finishing[abl.descriptor.Responsibility] = true
+	inr.sputnik.observe(FinishRequested, abl.descriptor, nil)
+	abl.controller.Finish()
+
+	to := inr.finishTimeout(abl)
+	if to <= 0 {
+		return
+	}
+
+	resp := abl.descriptor.Responsibility
+	inr.timers[resp] = time.AfterFunc(to, func() {
+		inr.put(abandonedmsg(resp))
+	})
+}
+
+func (inr *initiator) finishTimeout(abl *activeBlock) time.Duration {
+	if abl.descriptor.FinishTimeout > 0 {
+		return abl.descriptor.FinishTimeout
+	}
+	return inr.sputnik.fto
+}
+
+func (inr *initiator) processFinished(m Msg) {
+	resp, _ := m["__resp"].(string)
+
+	if _, exists := inr.actBlks.getABl(resp); !exists {
+		return // Removed block
+	}
+
+	if inr.isAbandoned(resp) {
+		return // Too late
+	}
+
+	if tm, exists := inr.timers[resp]; exists {
+		tm.Stop()
+		delete(inr.timers, resp)
+	}
+
+	inr.Lock()
+	inr.finished[resp] = true
+	inr.Unlock()
+
+	inr.unsubscribe(resp)
+
+	if abl, exists := inr.actBlks.getABl(resp); exists {
+		inr.sputnik.observe(FinishCompleted, abl.descriptor, nil)
+	}
+
+	inr.completeRemoval(resp, nil)
+
+	if !inr.finishStarted {
+		if inr.restarting[resp] {
+			abl, _ := inr.actBlks.getABl(resp)
+			inr.scheduleRestart(abl)
+		}
+		return // Block was finished individually
+	}
+
+	delete(inr.restarting, resp)
+
+	inr.finishReleased()
+	inr.nextFinishStage()
+	return
+}
+
+// Block did not finish in time - continue shutdown without it
+func (inr *initiator) processAbandoned(m Msg) {
+	resp, _ := m["__resp"].(string)
+
+	inr.Lock()
+	finished := inr.finished[resp]
+	inr.Unlock()
+
+	if finished {
+		return
+	}
+
+	delete(inr.timers, resp)
+	inr.abandoned = append(inr.abandoned, resp)
+
+	inr.unsubscribe(resp)
+
+	if abl, exists := inr.actBlks.getABl(resp); exists {
+		err := fmt.Errorf("Finish of [%s,%s] exceeded deadline, block abandoned", abl.descriptor.Name, resp)
+		inr.addBlockError(err)
+		inr.sputnik.observe(FinishCompleted, abl.descriptor, err)
+		inr.completeRemoval(resp, err)
+	}
+
+	if resp == DefaultConnectorResponsibility && inr.sputnik.cnt != nil {
+		// connector block hangs, close connection instead of it
+		inr.sputnik.cnt.Disconnect()
+	}
+
+	if !inr.finishStarted {
+		if inr.restarting[resp] {
+			abl, _ := inr.actBlks.getABl(resp)
+			inr.scheduleRestart(abl)
+		}
+		return // Block was finished individually
+	}
+
+	delete(inr.restarting, resp)
+
+	inr.finishReleased()
+	inr.nextFinishStage()
+	return
+}
+
+func (inr *initiator) forgetAbandoned(resp string) {
+	for i, ab := range inr.abandoned {
+		if ab == resp {
+			inr.abandoned = append(inr.abandoned[:i], inr.abandoned[i+1:]...)
+			return
+		}
+	}
+}
+
+func (inr *initiator) isAbandoned(resp string) bool {
+	for _, ab := range inr.abandoned {
+		if ab == resp {
+			return true
+		}
+	}
+	return false
+}
+
+// Shutdown has 2 stages:
+//   - finish of all blocks except connector
+//   - finish of connector
+func (inr *initiator) nextFinishStage() {
 	inr.finishedBlks++
 	if inr.finishedBlks == inr.expectFinished {
 		if inr.connector == nil {
-			inr.q.CancelMT() // stop main loop
+			inr.q.cancel() // stop main loop
 			return
 		}
 		inr.finishedBlks = 0
 		inr.expectFinished = 1
-		inr.connector.Finish()
+		cbl, _ := inr.actBlks.getABl(DefaultConnectorResponsibility)
 		inr.connector = nil
+		if inr.isFinished(cbl.descriptor.Responsibility) || inr.isAbandoned(cbl.descriptor.Responsibility) {
+			inr.q.cancel()
+			return
+		}
+		if !inr.isFinishing(cbl) {
+			inr.finishBlock(cbl)
+		}
+	}
+	return
+}
+
+func (inr *initiator) processPanic(m Msg) {
+	pe, ok := m["__panic"].(*PanicError)
+	if !ok {
+		return
+	}
+
+	inr.addBlockError(pe)
+
+	abl, ok := inr.currentBlock(m)
+	if !ok || inr.restarting[abl.descriptor.Responsibility] {
+		return
+	}
+
+	if inr.supervise(abl, true) {
+		return
+	}
+
+	switch inr.sputnik.pp {
+	case PanicIgnore:
+		return
+	case PanicFinishBlock:
+		if inr.finishStarted || inr.isFinishing(abl) {
+			return
+		}
+		inr.finishBlock(abl)
+	default:
+		inr.shutdown(ExitPanic, "", pe)
 	}
 	return
 }
@@ -279,29 +694,46 @@ const (
 	finishedMsg           = "finished"
 	serverConnectedMsg    = "serverConnected"
 	serverDisconnectedMsg = "serverDisconnected"
+	abandonedMsg          = "abandoned"
+	blockPanicMsg         = "blockPanic"
 )
 
-func FinishMsg() Msg {
+// Control messages of sputnik jump ahead of other messages
+func controlmsg(name string) Msg {
 	msg := make(Msg)
-	msg["__name"] = finishMsg
+	msg["__name"] = name
+	msg["__priority"] = PriorityHigh
 	return msg
 }
 
+func FinishMsg() Msg {
+	return controlmsg(finishMsg)
+}
+
 func FinishedMsg() Msg {
-	msg := make(Msg)
-	msg["__name"] = finishedMsg
-	return msg
+	return controlmsg(finishedMsg)
 }
 
 func serverconnectedmsg(conn ServerConnection) Msg {
-	msg := make(Msg)
-	msg["__name"] = serverConnectedMsg
+	msg := controlmsg(serverConnectedMsg)
 	msg["__conn"] = conn
 	return msg
 }
 
 func serverdisconnectedmsg() Msg {
-	msg := make(Msg)
-	msg["__name"] = serverDisconnectedMsg
+	return controlmsg(serverDisconnectedMsg)
+}
+
+func abandonedmsg(resp string) Msg {
+	msg := controlmsg(abandonedMsg)
+	msg["__resp"] = resp
+	return msg
+}
+
+func blockpanicmsg(cn *controller, pe *PanicError) Msg {
+	msg := controlmsg(blockPanicMsg)
+	msg["__resp"] = pe.Descriptor.Responsibility
+	msg["__cntr"] = cn
+	msg["__panic"] = pe
 	return msg
 }
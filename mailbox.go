@@ -0,0 +1,247 @@
+package sputnik
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Reaction of the mailbox of the block on overflow
+type OverflowPolicy int
+
+const (
+	// Sender waits for free place (see SendContext for waiting with deadline)
+	OverflowBlock OverflowPolicy = iota
+	// Message is rejected, Send returns false
+	OverflowReject
+	// The oldest message within mailbox is dropped
+	OverflowDropOldest
+	// The new message is dropped, Send returns true
+	OverflowDropNewest
+)
+
+// Priority of the message.
+// Messages with PriorityHigh are processed before all messages with PriorityNormal
+// and are not limited by capacity of the mailbox.
+// Control messages of sputnik (finish, server connect/disconnect, ...) have PriorityHigh.
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	PriorityHigh
+)
+
+// Priority of the message is stored under reserved key "__priority"
+func msgPriority(msg Msg) Priority {
+	p, _ := msg["__priority"].(Priority)
+	return p
+}
+
+// Returned by SendContext for rejected message
+var ErrMailboxFull = errors.New("mailbox of the block is full")
+
+// Counters of the mailbox
+type MailboxStats struct {
+	// 0 - unbounded
+	Capacity int
+	Policy   OverflowPolicy
+	// Current number of messages
+	Len int
+	// Messages dropped because of OverflowDropOldest|OverflowDropNewest
+	Dropped uint64
+	// Messages rejected because of OverflowReject
+	Rejected uint64
+}
+
+// Queue of the messages of the block.
+// Unbounded if capacity <= 0.
+type mailbox struct {
+	lock      sync.Mutex
+	capacity  int
+	policy    OverflowPolicy
+	items     []Msg
+	urgent    []Msg
+	cancelled bool
+	dropped   uint64
+	rejected  uint64
+	// Signals about new message
+	avail chan struct{}
+	// Closed (and replaced) when place is freed in full mailbox
+	space chan struct{}
+	// Closed after cancel
+	done chan struct{}
+	// Optional, called for every message dropped on overflow (ErrMailboxFull)
+	// or discarded by cancel (ErrBlockFinished)
+	discard func(msg Msg, reason error)
+}
+
+func newMailbox(capacity int, policy OverflowPolicy) *mailbox {
+	return &mailbox{
+		capacity: capacity,
+		policy:   policy,
+		avail:    make(chan struct{}, 1),
+		space:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (mb *mailbox) put(ctx context.Context, msg Msg, prio Priority) error {
+	for {
+		mb.lock.Lock()
+
+		if mb.cancelled {
+			mb.lock.Unlock()
+			return ErrBlockFinished
+		}
+
+		if prio == PriorityHigh {
+			mb.urgent = append(mb.urgent, msg)
+			mb.lock.Unlock()
+			mb.notify()
+			return nil
+		}
+
+		if mb.capacity <= 0 || len(mb.items) < mb.capacity {
+			mb.items = append(mb.items, msg)
+			mb.lock.Unlock()
+			mb.notify()
+			return nil
+		}
+
+		switch mb.policy {
+		case OverflowReject:
+			mb.rejected++
+			mb.lock.Unlock()
+			return ErrMailboxFull
+		case OverflowDropNewest:
+			mb.dropped++
+			mb.lock.Unlock()
+			mb.drop(msg, ErrMailboxFull)
+			return nil
+		case OverflowDropOldest:
+			oldest := mb.items[0]
+			mb.items[0] = nil
+			mb.items = append(mb.items[1:], msg)
+			mb.dropped++
+			mb.lock.Unlock()
+			mb.drop(oldest, ErrMailboxFull)
+			return nil
+		}
+
+		space := mb.space
+		mb.lock.Unlock()
+
+		select {
+		case <-space:
+		case <-mb.done:
+			return ErrBlockFinished
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Like put, but returns ErrMailboxFull instead of waiting on full mailbox (OverflowBlock).
+// Used for messages sent by sputnik.
+func (mb *mailbox) offer(msg Msg, prio Priority) error {
+	err := mb.put(expired, msg, prio)
+	if errors.Is(err, context.Canceled) {
+		return ErrMailboxFull
+	}
+	return err
+}
+
+// Context of put without waiting
+var expired = func() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}()
+
+// Waits for the next message. Returns false after cancel.
+func (mb *mailbox) get() (Msg, bool) {
+	for {
+		mb.lock.Lock()
+
+		if mb.cancelled {
+			mb.lock.Unlock()
+			return nil, false
+		}
+
+		if len(mb.urgent) > 0 {
+			msg := mb.urgent[0]
+			mb.urgent[0] = nil
+			mb.urgent = mb.urgent[1:]
+			mb.lock.Unlock()
+			return msg, true
+		}
+
+		if len(mb.items) > 0 {
+			wasFull := mb.capacity > 0 && len(mb.items) >= mb.capacity
+			msg := mb.items[0]
+			mb.items[0] = nil
+			mb.items = mb.items[1:]
+			if wasFull {
+				close(mb.space)
+				mb.space = make(chan struct{})
+			}
+			mb.lock.Unlock()
+			return msg, true
+		}
+
+		mb.lock.Unlock()
+
+		select {
+		case <-mb.avail:
+		case <-mb.done:
+		}
+	}
+}
+
+// Pending messages are discarded
+func (mb *mailbox) cancel() {
+	mb.lock.Lock()
+
+	if mb.cancelled {
+		mb.lock.Unlock()
+		return
+	}
+	mb.cancelled = true
+	pending := append(mb.urgent, mb.items...)
+	mb.items = nil
+	mb.urgent = nil
+	close(mb.done)
+
+	mb.lock.Unlock()
+
+	for _, msg := range pending {
+		mb.drop(msg, ErrBlockFinished)
+	}
+}
+
+// Reports message, which will not be processed
+func (mb *mailbox) drop(msg Msg, reason error) {
+	if mb.discard != nil {
+		mb.discard(msg, reason)
+	}
+}
+
+func (mb *mailbox) notify() {
+	select {
+	case mb.avail <- struct{}{}:
+	default:
+	}
+}
+
+func (mb *mailbox) stats() MailboxStats {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+
+	return MailboxStats{
+		Capacity: mb.capacity,
+		Policy:   mb.policy,
+		Len:      len(mb.items) + len(mb.urgent),
+		Dropped:  mb.dropped,
+		Rejected: mb.rejected,
+	}
+}
@@ -0,0 +1,91 @@
+package sputnik
+
+// Middleware wraps callbacks of the block, e.g. for logging, metrics,
+// authorization or validation of messages.
+//
+// Middlewares are applied in the order given: the first one is the outermost.
+// Middlewares of sputnik (WithMiddleware) wrap middlewares of the block (WithBlockMiddleware).
+// Middlewares of sputnik are not applied to infrastructure blocks (finisher, connector, dead-letter).
+type Middleware struct {
+	// Wraps OnMsg of the block.
+	// Middleware may short-circuit the message - don't call next.
+	OnMsg func(bd BlockDescriptor, next OnMsg) OnMsg
+
+	// Optional wrapper of Init, Finish, OnServerConnect, OnServerDisconnect and OnConfigChange.
+	// callback - name of the callback (InitCallback, FinishCallback, ...).
+	// Error returned for Init is reported as failure of Init, for other callbacks is ignored.
+	Around func(bd BlockDescriptor, callback string, next func() error) error
+}
+
+// Middlewares of the block
+func WithBlockMiddleware(mws ...Middleware) BlockOption {
+	return func(b *Block) {
+		b.mws = append(b.mws, mws...)
+	}
+}
+
+// Returns copy of the block with callbacks wrapped by middlewares
+func wrapBlock(bd BlockDescriptor, b *Block, mws []Middleware) *Block {
+	if len(mws) == 0 {
+		return b
+	}
+
+	wb := *b
+
+	if b.onMsg != nil {
+		wb.onMsg = chainMsg(bd, b.onMsg, mws)
+	}
+
+	if b.init != nil {
+		wb.init = func(cf ConfFactory) error {
+			return around(bd, InitCallback, mws, func() error { return b.init(cf) })
+		}
+	}
+
+	if b.finish != nil {
+		wb.finish = func(init bool) {
+			around(bd, FinishCallback, mws, func() error { b.finish(init); return nil })
+		}
+	}
+
+	if b.onConnect != nil {
+		wb.onConnect = func(connection ServerConnection) {
+			around(bd, OnConnectCallback, mws, func() error { b.onConnect(connection); return nil })
+		}
+	}
+
+	if b.onDisconnect != nil {
+		wb.onDisconnect = func() {
+			around(bd, OnDisconnectCallback, mws, func() error { b.onDisconnect(); return nil })
+		}
+	}
+
+	if b.onConfChange != nil {
+		wb.onConfChange = func(confName string, cf ConfFactory) {
+			around(bd, OnConfigChangeCallback, mws, func() error { b.onConfChange(confName, cf); return nil })
+		}
+	}
+
+	return &wb
+}
+
+func chainMsg(bd BlockDescriptor, h OnMsg, mws []Middleware) OnMsg {
+	for i := len(mws) - 1; i >= 0; i-- {
+		if mws[i].OnMsg != nil {
+			h = mws[i].OnMsg(bd, h)
+		}
+	}
+	return h
+}
+
+func around(bd BlockDescriptor, callback string, mws []Middleware, fn func() error) error {
+	next := fn
+	for i := len(mws) - 1; i >= 0; i-- {
+		if mws[i].Around == nil {
+			continue
+		}
+		mw, inner := mws[i], next
+		next = func() error { return mw.Around(bd, callback, inner) }
+	}
+	return next()
+}
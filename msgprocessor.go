@@ -1,41 +1,171 @@
 package sputnik
 
 import (
+	"context"
+	"hash/fnv"
 	"sync"
-
-	"github.com/g41797/kissngoqueue"
 )
 
 // Helper of communicator. All messages send to block
-// are processed using queue on the same goroutine.
+// are processed using mailbox on the same goroutine
+// or on pool of workers (see WithWorkers).
 type msgProcessor struct {
-	fnc  OnMsg
-	q    *kissngoqueue.Queue[Msg]
-	once sync.Once
+	fnc      OnMsg
+	capacity int
+	policy   OverflowPolicy
+	workers  int
+	key      PartitionKey
+
+	// Mailbox of the block shared by workers or,
+	// for partitioned workers, own mailbox of every worker
+	queues []*mailbox
+
+	lock    sync.Mutex
+	started bool
+	stopped bool
+	// Counts running goroutines of the processor
+	wg sync.WaitGroup
+
+	// Closed after cancel
+	done chan struct{}
 }
 
-func newMsgProcessor(fnc OnMsg) *msgProcessor {
+func newMsgProcessor(fnc OnMsg, capacity int, policy OverflowPolicy, workers int, key PartitionKey) *msgProcessor {
 	pr := msgProcessor{
-		fnc: fnc,
-		q:   kissngoqueue.NewQueue[Msg](),
+		fnc:      fnc,
+		capacity: capacity,
+		policy:   policy,
+		workers:  workers,
+		key:      key,
+		done:     make(chan struct{}),
+	}
+
+	if !pr.partitioned() {
+		pr.queues = []*mailbox{newMailbox(capacity, policy)}
+		return &pr
+	}
+
+	// Capacity of the block is divided between workers
+	if capacity > 0 {
+		capacity = (capacity + workers - 1) / workers
+	}
+
+	pr.queues = make([]*mailbox, workers)
+	for i := range pr.queues {
+		pr.queues[i] = newMailbox(capacity, policy)
 	}
 	return &pr
 }
 
 func (pr *msgProcessor) submit(msg Msg) bool {
-	pr.once.Do(func() { go pr.process() })
-	pok := pr.q.PutMT(msg)
-	return pok
+	return pr.submitContext(context.Background(), msg) == nil
+}
+
+func (pr *msgProcessor) submitContext(ctx context.Context, msg Msg) error {
+	pr.start()
+	return pr.queue(msg).put(ctx, msg, msgPriority(msg))
+}
+
+// Submit without waiting on full mailbox
+func (pr *msgProcessor) offer(msg Msg) error {
+	pr.start()
+	return pr.queue(msg).offer(msg, msgPriority(msg))
+}
+
+// Processing is started with the first message
+func (pr *msgProcessor) start() {
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+
+	if pr.started || pr.stopped {
+		return
+	}
+	pr.started = true
+
+	// Without key every free worker gets the next message of shared mailbox
+	perQueue := 1
+	if pr.concurrent() && !pr.partitioned() {
+		perQueue = pr.workers
+	}
+
+	for _, q := range pr.queues {
+		pr.wg.Add(perQueue)
+		for i := 0; i < perQueue; i++ {
+			go pr.work(q)
+		}
+	}
 }
 
+func (pr *msgProcessor) concurrent() bool {
+	return pr.workers > 1
+}
+
+// Messages with the same key are processed by the same worker
+func (pr *msgProcessor) partitioned() bool {
+	return pr.concurrent() && pr.key != nil
+}
+
+// Pending messages are discarded
 func (pr *msgProcessor) cancel() {
-	pr.q.CancelMT()
+	pr.lock.Lock()
+	if pr.stopped {
+		pr.lock.Unlock()
+		return
+	}
+	pr.stopped = true
+	close(pr.done)
+	pr.lock.Unlock()
+
+	for _, q := range pr.queues {
+		q.cancel()
+	}
 	return
 }
 
-func (pr *msgProcessor) process() {
+// Reports messages dropped or discarded by queues of the processor
+func (pr *msgProcessor) setDiscard(discard func(msg Msg, reason error)) {
+	for _, q := range pr.queues {
+		q.discard = discard
+	}
+}
+
+// Waits for return of in-flight OnMsg after cancel
+func (pr *msgProcessor) wait() {
+	pr.wg.Wait()
+}
+
+// Counters of all queues of the block
+func (pr *msgProcessor) stats() MailboxStats {
+	result := MailboxStats{Capacity: pr.capacity, Policy: pr.policy}
+
+	for _, q := range pr.queues {
+		qs := q.stats()
+		result.Len += qs.Len
+		result.Dropped += qs.Dropped
+		result.Rejected += qs.Rejected
+	}
+	return result
+}
+
+func (pr *msgProcessor) queue(msg Msg) *mailbox {
+	if len(pr.queues) == 1 {
+		return pr.queues[0]
+	}
+	return pr.queues[pr.partition(msg)]
+}
+
+func (pr *msgProcessor) partition(msg Msg) int {
+	h := fnv.New32a()
+	h.Write([]byte(pr.key(msg)))
+	return int(h.Sum32() % uint32(pr.workers))
+}
+
+// Processes messages of the queue till cancel
+func (pr *msgProcessor) work(q *mailbox) {
+	defer pr.wg.Done()
+
 	for {
-		msg, ok := pr.q.Get()
+		msg, ok := q.get()
 		if !ok {
 			break
 		}
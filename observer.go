@@ -0,0 +1,107 @@
+package sputnik
+
+import "time"
+
+// LifecycleEventKind - point of the life cycle of block or process
+type LifecycleEventKind int
+
+const (
+	BlockCreated LifecycleEventKind = iota
+	InitStarted
+	InitSucceeded
+	InitFailed
+	RunStarted
+	RunReturned
+	ConnectDelivered
+	DisconnectDelivered
+	FinishRequested
+	FinishCompleted
+	ProcessExit
+)
+
+func (k LifecycleEventKind) String() string {
+	switch k {
+	case BlockCreated:
+		return "block created"
+	case InitStarted:
+		return "init started"
+	case InitSucceeded:
+		return "init succeeded"
+	case InitFailed:
+		return "init failed"
+	case RunStarted:
+		return "run started"
+	case RunReturned:
+		return "run returned"
+	case ConnectDelivered:
+		return "connect delivered"
+	case DisconnectDelivered:
+		return "disconnect delivered"
+	case FinishRequested:
+		return "finish requested"
+	case FinishCompleted:
+		return "finish completed"
+	case ProcessExit:
+		return "process exit"
+	}
+	return "unknown"
+}
+
+type LifecycleEvent struct {
+	Kind LifecycleEventKind
+
+	// Descriptor of the block, empty for ProcessExit
+	Descriptor BlockDescriptor
+
+	Time time.Time
+
+	// Error for InitFailed, FinishCompleted (abandoned block), ProcessExit
+	Err error
+}
+
+// LifecycleObserver is used for telemetry of the process.
+// Observe is called from different goroutines and should not block.
+type LifecycleObserver interface {
+	Observe(ev LifecycleEvent)
+}
+
+func (sp *Sputnik) observe(kind LifecycleEventKind, bd BlockDescriptor, err error) {
+	if sp.obs == nil {
+		return
+	}
+	sp.obs.Observe(LifecycleEvent{kind, bd, time.Now(), err})
+}
+
+// Initializes the block with notifications of observer
+func (inr *initiator) initBlock(abl *activeBlock) error {
+	inr.sputnik.observe(InitStarted, abl.descriptor, nil)
+
+	err := abl.init(inr.sputnik.cnfFact)
+
+	if err != nil {
+		inr.sputnik.observe(InitFailed, abl.descriptor, err)
+		return err
+	}
+
+	inr.sputnik.observe(InitSucceeded, abl.descriptor, nil)
+	return nil
+}
+
+// Synchronous Finish(true) of initialized block
+func (inr *initiator) finishInitialized(abl *activeBlock) {
+	inr.sputnik.observe(FinishRequested, abl.descriptor, nil)
+	abl.finish()
+	inr.sputnik.observe(FinishCompleted, abl.descriptor, nil)
+}
+
+func (inr *initiator) connectBlock(abl *activeBlock, conn ServerConnection) {
+	if abl.controller.ServerConnected(conn) {
+		inr.sputnik.observe(ConnectDelivered, abl.descriptor, nil)
+	}
+}
+
+func (inr *initiator) disconnectBlock(abl *activeBlock) {
+	if abl.controller.serverDisconnected() {
+		inr.sputnik.observe(DisconnectDelivered, abl.descriptor, nil)
+	}
+}
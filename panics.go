@@ -0,0 +1,60 @@
+package sputnik
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicPolicy defines reaction of sputnik on panic within block callback.
+// Panic is always recovered, stack is captured and reported to initiator.
+type PanicPolicy int
+
+const (
+	// Graceful shutdown of the process (default)
+	PanicShutdown PanicPolicy = iota
+
+	// Finish only the block, other blocks continue to run
+	PanicFinishBlock
+
+	// Continue as if nothing happened
+	PanicIgnore
+)
+
+// PanicError describes recovered panic of block callback
+type PanicError struct {
+	Descriptor BlockDescriptor
+	Callback   string
+	Value      any
+	Stack      []byte
+}
+
+func (pe *PanicError) Error() string {
+	return fmt.Sprintf("panic in %s of [%s,%s]: %v", pe.Callback, pe.Descriptor.Name, pe.Descriptor.Responsibility, pe.Value)
+}
+
+// Names of callbacks used in PanicError
+const (
+	InitCallback           = "Init"
+	StartCallback          = "Start"
+	RunCallback            = "Run"
+	FinishCallback         = "Finish"
+	OnConnectCallback      = "OnServerConnect"
+	OnDisconnectCallback   = "OnServerDisconnect"
+	OnMsgCallback          = "OnMsg"
+	OnConfigChangeCallback = "OnConfigChange"
+	PartitionKeyCallback   = "PartitionKey"
+)
+
+// Runs callback of the block.
+// Recovered panic is returned as *PanicError.
+func protect(bd BlockDescriptor, callback string, fn func()) (pe *PanicError) {
+	defer func() {
+		if r := recover(); r != nil {
+			pe = &PanicError{bd, callback, r, debug.Stack()}
+		}
+	}()
+
+	fn()
+
+	return nil
+}
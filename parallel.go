@@ -0,0 +1,90 @@
+package sputnik
+
+import "sort"
+
+type initResult struct {
+	indx int
+	err  error
+}
+
+// Initializes blocks concurrently.
+// Block is initialized after initialization of all blocks it depends on.
+// Returns initialized blocks in order of initialization.
+// After the first failure new initializations are not started,
+// but running ones are completed.
+func (inr *initiator) initParallel(appBlks activeBlocks) (activeBlocks, error) {
+
+	index := make(map[string]int, len(appBlks))
+	for i, abl := range appBlks {
+		index[abl.descriptor.Responsibility] = i
+	}
+
+	pending := make([]int, len(appBlks))
+	dependants := make([][]int, len(appBlks))
+
+	for i, abl := range appBlks {
+		for _, dep := range abl.descriptor.DependsOn {
+			j, exists := index[dep]
+			if !exists {
+				continue
+			}
+			pending[i]++
+			dependants[j] = append(dependants[j], i)
+		}
+	}
+
+	ready := make([]int, 0)
+	for i := range appBlks {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	results := make(chan initResult)
+	running := 0
+
+	ibs := make(activeBlocks, 0)
+	var err error
+
+	for {
+		for err == nil && running < inr.sputnik.workers && len(ready) > 0 {
+			if err = inr.ctx.Err(); err != nil {
+				break
+			}
+
+			sort.Ints(ready)
+			next := ready[0]
+			ready = ready[1:]
+
+			running++
+			go func(i int) {
+				results <- initResult{i, inr.initBlock(appBlks[i])}
+			}(next)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		res := <-results
+		running--
+
+		if res.err != nil {
+			if err == nil {
+				err = res.err
+			}
+			continue
+		}
+
+		ibs = append(ibs, appBlks[res.indx])
+
+		for _, d := range dependants[res.indx] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	return ibs, err
+}
@@ -0,0 +1,115 @@
+package sputnik
+
+import "sync"
+
+// In-process publish/subscribe between blocks.
+// Subscribers are controllers of the blocks, so messages are delivered
+// via the queue of the block and OnMsg is called sequentially as for Send.
+// Subscriptions of the block are removed by initiator after finish
+// (or abandon) of the block, restarted block should subscribe again.
+type topics struct {
+	lock sync.RWMutex
+	subs map[string]map[*controller]struct{}
+}
+
+func newTopics() *topics {
+	return &topics{subs: make(map[string]map[*controller]struct{})}
+}
+
+func (tp *topics) subscribe(topic string, cn *controller) {
+	tp.lock.Lock()
+	defer tp.lock.Unlock()
+
+	cns, exists := tp.subs[topic]
+	if !exists {
+		cns = make(map[*controller]struct{})
+		tp.subs[topic] = cns
+	}
+	cns[cn] = struct{}{}
+}
+
+func (tp *topics) unsubscribe(topic string, cn *controller) {
+	tp.lock.Lock()
+	defer tp.lock.Unlock()
+
+	if cns, exists := tp.subs[topic]; exists {
+		delete(cns, cn)
+		if len(cns) == 0 {
+			delete(tp.subs, topic)
+		}
+	}
+}
+
+// Removes all subscriptions of the block
+func (tp *topics) unsubscribeAll(cn *controller) {
+	tp.lock.Lock()
+	defer tp.lock.Unlock()
+
+	for topic, cns := range tp.subs {
+		delete(cns, cn)
+		if len(cns) == 0 {
+			delete(tp.subs, topic)
+		}
+	}
+}
+
+func (tp *topics) subscribers(topic string) []*controller {
+	tp.lock.RLock()
+	defer tp.lock.RUnlock()
+
+	cns := tp.subs[topic]
+	result := make([]*controller, 0, len(cns))
+	for cn := range cns {
+		result = append(result, cn)
+	}
+	return result
+}
+
+// Subscribe controlled block to the topic.
+// Returns false if controlled block has not OnMsg callback.
+// Usually called by the block itself during Run:
+//
+//	bc.Subscribe("alerts")
+func (cn *controller) Subscribe(topic string) bool {
+	if cn.block.onMsg == nil {
+		return false
+	}
+
+	cn.actBlks.bus.subscribe(topic, cn)
+	return true
+}
+
+func (cn *controller) Unsubscribe(topic string) {
+	cn.actBlks.bus.unsubscribe(topic, cn)
+}
+
+// Publish sends copy of msg to every subscriber of the topic.
+// Name of the topic is stored in the message under key "__topic".
+// Returns number of subscribers received the message.
+func (cn *controller) Publish(topic string, msg Msg) int {
+	return cn.publishFrom(cn.self(), topic, msg)
+}
+
+func (cn *controller) publishFrom(from sender, topic string, msg Msg) int {
+	if msg == nil {
+		return 0
+	}
+
+	sent := 0
+	for _, scn := range cn.actBlks.bus.subscribers(topic) {
+		m := copymsg(msg)
+		m["__topic"] = topic
+
+		if scn.sendFrom(from, m) {
+			sent++
+		}
+	}
+	return sent
+}
+
+// Removes subscriptions of finished block
+func (inr *initiator) unsubscribe(resp string) {
+	if abl, exists := inr.actBlks.getABl(resp); exists {
+		inr.actBlks.bus.unsubscribeAll(abl.currentController())
+	}
+}
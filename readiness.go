@@ -0,0 +1,101 @@
+package sputnik
+
+import (
+	"context"
+	"fmt"
+)
+
+// Name of the message sent by initiator to application blocks created
+// with WithReadyNotice after readiness of the process.
+//
+// Block is ready to work:
+//   - after call of BlockCommunicator.Ready, if block was created with WithManualReady
+//   - otherwise after successful Init and return from Start callback (if it was set by WithStart),
+//     Run is called right after it. Readiness does not mean that Run already did something.
+//
+// Process is ready after readiness of all blocks and, if ServerConnector
+// was set, successful connection to the server.
+// Readiness is reported once:
+//   - blocks created with WithReadyNotice receive message with name AllReadyMsgName,
+//     the message has PriorityHigh and is not limited by capacity of the mailbox
+//   - Flight.WaitReady returns nil
+const AllReadyMsgName = "allReady"
+
+// Wait readiness of the process.
+// Returns error if ctx was cancelled or the flight finished before readiness.
+func (fl *Flight) WaitReady(ctx context.Context) error {
+	select {
+	case <-fl.inr.allReady:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-fl.inr.done:
+		select {
+		case <-fl.inr.allReady:
+			return nil
+		default:
+		}
+		return fmt.Errorf("flight finished before readiness")
+	}
+}
+
+func (cn *controller) Ready() {
+	cn.actBlks.initiator().Send(blockreadymsg(cn))
+}
+
+func (inr *initiator) processBlockReady(m Msg) {
+	abl, ok := inr.currentBlock(m)
+	if !ok {
+		return
+	}
+
+	inr.ready[abl.descriptor.Responsibility] = true
+
+	inr.checkReadiness()
+}
+
+// Broadcasts readiness of the process once
+func (inr *initiator) checkReadiness() {
+	if inr.readyReported || inr.finishStarted {
+		return
+	}
+
+	if inr.sputnik.cnt != nil && !inr.connected {
+		return
+	}
+
+	abls := inr.actBlks.appBlocks()
+
+	for _, abl := range abls {
+		if !inr.ready[abl.descriptor.Responsibility] {
+			return
+		}
+	}
+
+	inr.readyReported = true
+	close(inr.allReady)
+
+	for _, abl := range abls {
+		cn := abl.currentController()
+		if cn == nil || !cn.block.readyNotice || cn.block.onMsg == nil {
+			continue
+		}
+		cn.sendWithPriorityFrom(inr.actBlks.initiator().self(), allreadymsg(), PriorityHigh)
+	}
+}
+
+const blockReadyMsg = "blockReady"
+
+func blockreadymsg(cn *controller) Msg {
+	msg := make(Msg)
+	msg["__name"] = blockReadyMsg
+	msg["__resp"] = cn.descriptor.Responsibility
+	msg["__cntr"] = cn
+	return msg
+}
+
+func allreadymsg() Msg {
+	msg := make(Msg)
+	msg["__name"] = AllReadyMsgName
+	return msg
+}
@@ -0,0 +1,115 @@
+package sputnik
+
+import "strings"
+
+// ConfigWatcher reports changes of configuration.
+// Watching is started after start of Run of all blocks and stopped
+// during shutdown.
+type ConfigWatcher interface {
+	// Starts watching.
+	// notify is called with name of changed configuration.
+	Watch(notify func(confName string)) (stop func())
+}
+
+// ConfigChangePolicy defines reaction on change of configuration
+// for block without OnConfigChange callback
+type ConfigChangePolicy int
+
+const (
+	// Block is left alone (default)
+	ConfigChangeIgnore ConfigChangePolicy = iota
+
+	// Block is restarted like by supervisor
+	ConfigChangeRestart
+)
+
+// ConfigChangedMsg creates message for initiator about changed configuration.
+// Blocks, which requested this configuration name from ConfFactory are notified.
+// Empty confName - all configurations were changed.
+//
+//	ibc, _ := bc.Communicator(sputnik.InitiatorResponsibility)
+//	ibc.Send(sputnik.ConfigChangedMsg("syslogreceiver"))
+func ConfigChangedMsg(confName string) Msg {
+	msg := make(Msg)
+	msg["__name"] = configChangedMsg
+	msg["__conf"] = confName
+	return msg
+}
+
+const configChangedMsg = "configChanged"
+
+// Returns ConfFactory, which records names of requested configurations
+func (abl *activeBlock) recordingFactory(cf ConfFactory) ConfFactory {
+	return func(confName string, result any) error {
+		abl.lock.Lock()
+		if abl.confNames == nil {
+			abl.confNames = make(map[string]struct{})
+		}
+		abl.confNames[strings.ToLower(confName)] = struct{}{}
+		abl.lock.Unlock()
+
+		return cf(confName, result)
+	}
+}
+
+// Names of configurations requested by the block and affected by the change
+func (abl *activeBlock) affectedConfs(confName string) []string {
+	abl.lock.RLock()
+	defer abl.lock.RUnlock()
+
+	result := make([]string, 0)
+	for name := range abl.confNames {
+		if confName == "" || strings.EqualFold(name, confName) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+func (inr *initiator) processConfigChanged(m Msg) {
+	confName, _ := m["__conf"].(string)
+
+	if inr.finishStarted {
+		return
+	}
+
+	for _, abl := range inr.actBlks.appBlocks() {
+		if inr.isFinishing(abl) || inr.isFinished(abl.descriptor.Responsibility) {
+			continue
+		}
+
+		names := abl.affectedConfs(confName)
+		if len(names) == 0 {
+			continue
+		}
+
+		if abl.controller.configChanged(names, abl.recordingFactory(inr.sputnik.cnfFact)) {
+			continue
+		}
+
+		if inr.sputnik.ccp == ConfigChangeRestart && !inr.isInfrastructure(abl.descriptor.Responsibility) {
+			inr.restartBlock(abl)
+		}
+	}
+	return
+}
+
+func (inr *initiator) isInfrastructure(resp string) bool {
+	return inr.actBlks.isInfrastructure(resp)
+}
+
+func (inr *initiator) startConfigWatcher() {
+	if inr.sputnik.cw == nil {
+		return
+	}
+
+	inr.stopWatcher = inr.sputnik.cw.Watch(func(confName string) {
+		inr.put(ConfigChangedMsg(confName))
+	})
+}
+
+func (inr *initiator) stopConfigWatcher() {
+	if inr.stopWatcher != nil {
+		inr.stopWatcher()
+	}
+}
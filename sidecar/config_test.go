@@ -3,8 +3,10 @@ package sidecar_test
 import (
 	"embed"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/g41797/sputnik/sidecar"
 )
@@ -113,3 +115,35 @@ func TestUseTemporaryFolderForConfiguration(t *testing.T) {
 	}
 
 }
+
+func TestConfigWatcher(t *testing.T) {
+
+	dir := t.TempDir()
+
+	fPath := filepath.Join(dir, "example.json")
+
+	if err := os.WriteFile(fPath, []byte(`{"SECONDINT": 300}`), 0644); err != nil {
+		t.Fatalf("WriteFile error:%v", err)
+	}
+
+	changed := make(chan string, 10)
+
+	stop := sidecar.ConfigWatcher(dir, 50*time.Millisecond).Watch(func(confName string) {
+		changed <- confName
+	})
+	defer stop()
+
+	mtime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(fPath, mtime, mtime); err != nil {
+		t.Fatalf("Chtimes error:%v", err)
+	}
+
+	select {
+	case confName := <-changed:
+		if confName != "example" {
+			t.Errorf("Expected example Actual %s", confName)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("change of configuration was not reported")
+	}
+}
@@ -26,17 +26,26 @@ func Start(cntr sputnik.ServerConnector) {
 	}
 
 	rnr.Wait()
-	return
 
+	if code := sputnik.ExitCode(rnr.Err()); code != 0 {
+		fmt.Fprintln(os.Stderr, rnr.Err())
+		os.Exit(code)
+	}
+
+	return
 }
 
 const brokerCheckTimeOut = time.Second
 
+const configCheckTimeOut = time.Second * 5
+
 type Runner struct {
 	// ShootDown
 	kill sputnik.ShootDown
 	// Signalling channel
 	done chan struct{}
+	// Result of Launch
+	err error
 }
 
 func StartRunner(confFolder string, cntr sputnik.ServerConnector) (*Runner, error) {
@@ -81,10 +90,20 @@ func (rnr *Runner) Wait() {
 	return
 }
 
+// Err returns result of the flight: nil or *sputnik.ExitError
+// Valid after Wait
+func (rnr *Runner) Err() error {
+	if rnr == nil {
+		return nil
+	}
+	return rnr.err
+}
+
 type runnerInfo struct {
-	cfact     sputnik.ConfFactory
-	cnt       sputnik.ServerConnector
-	appBlocks []sputnik.BlockDescriptor
+	confFolder string
+	cfact      sputnik.ConfFactory
+	cnt        sputnik.ServerConnector
+	appBlocks  []sputnik.BlockDescriptor
 }
 
 func prepare(confFolder string, cntr sputnik.ServerConnector) (*runnerInfo, error) {
@@ -98,6 +117,8 @@ func prepare(confFolder string, cntr sputnik.ServerConnector) (*runnerInfo, erro
 
 	ri := runnerInfo{}
 
+	ri.confFolder = confFolder
+
 	ri.cfact = ConfigFactory(confFolder)
 
 	ri.cnt = cntr
@@ -113,6 +134,7 @@ func (rnr *Runner) Start(ri *runnerInfo) error {
 		sputnik.WithAppBlocks(ri.appBlocks),
 		sputnik.WithConfFactory(ri.cfact),
 		sputnik.WithConnector(ri.cnt, brokerCheckTimeOut),
+		sputnik.WithConfigWatcher(ConfigWatcher(ri.confFolder, configCheckTimeOut)),
 	)
 
 	if err != nil {
@@ -128,7 +150,7 @@ func (rnr *Runner) Start(ri *runnerInfo) error {
 	rnr.done = make(chan struct{})
 
 	go func(l sputnik.Launch, done chan struct{}) {
-		l()
+		rnr.err = l()
 		close(done)
 	}(launch, rnr.done)
 
@@ -145,7 +167,9 @@ func ReadAppBlocks(confFolder string) ([]sputnik.BlockDescriptor, error) {
 
 	var result []sputnik.BlockDescriptor
 
-	json.Unmarshal([]byte(blocksRaw), &result)
+	if err = json.Unmarshal([]byte(blocksRaw), &result); err != nil {
+		return nil, fmt.Errorf("%s: %w", fPath, err)
+	}
 
 	return result, nil
 }
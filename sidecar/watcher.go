@@ -0,0 +1,76 @@
+package sidecar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/g41797/sputnik"
+)
+
+// ConfigWatcher returns implementation of sputnik.ConfigWatcher for configuration folder
+// - folder is checked every interval
+// - name of changed (or new) <confName>.json is reported
+func ConfigWatcher(cfPath string, interval time.Duration) sputnik.ConfigWatcher {
+	return &folderWatcher{confPath: cfPath, interval: interval}
+}
+
+type folderWatcher struct {
+	confPath string
+	interval time.Duration
+}
+
+func (fw *folderWatcher) Watch(notify func(confName string)) (stop func()) {
+	done := make(chan struct{})
+
+	mtimes := fw.scan()
+
+	go func() {
+		ticker := time.NewTicker(fw.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				current := fw.scan()
+				for name, mt := range current {
+					if prev, exists := mtimes[name]; !exists || !prev.Equal(mt) {
+						notify(name)
+					}
+				}
+				mtimes = current
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// Modification times of configuration files
+func (fw *folderWatcher) scan() map[string]time.Time {
+	result := make(map[string]time.Time)
+
+	entries, err := os.ReadDir(fw.confPath)
+	if err != nil {
+		return result
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		confName := strings.ToLower(strings.TrimSuffix(entry.Name(), ".json"))
+		result[confName] = info.ModTime()
+	}
+
+	return result
+}
@@ -1,6 +1,7 @@
 package sputnik
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -19,11 +20,12 @@ type Sputnik struct {
 	fbd BlockDescriptor
 
 	// Application blocks
-	// Order in the list defines order of creation and initialization
+	// Order of creation and initialization is defined by dependencies
+	// between blocks, order in the list is used as tie-breaker
 	appBlocks []BlockDescriptor
 
 	// Block Factories of the process
-	blkFacts BlockFactories
+	reg *Registry
 
 	// Server connector plug-in
 	cnt ServerConnector
@@ -33,6 +35,33 @@ type Sputnik struct {
 
 	// Descriptor of used connector block
 	cnd BlockDescriptor
+
+	// Default deadline for Finish of the block
+	fto time.Duration
+
+	// Reaction on panic within block callback
+	pp PanicPolicy
+
+	// Max number of concurrent Init of blocks
+	workers int
+
+	// Watcher of configuration changes
+	cw ConfigWatcher
+
+	// Reaction on configuration change for blocks without OnConfigChange
+	ccp ConfigChangePolicy
+
+	// Observer of life cycle events
+	obs LifecycleObserver
+
+	// Sink for tracing of messages
+	ts TraceSink
+
+	// Descriptor of optional dead-letter block
+	dlbd *BlockDescriptor
+
+	// Middlewares of application blocks
+	mws []Middleware
 }
 
 type SputnikOption func(sp *Sputnik)
@@ -55,9 +84,19 @@ func WithAppBlocks(appBlocks []BlockDescriptor) SputnikOption {
 	}
 }
 
+// Block factories of the process.
+// Registry with copy of blkFacts is used.
 func WithBlockFactories(blkFacts BlockFactories) SputnikOption {
 	return func(sp *Sputnik) {
-		sp.blkFacts = blkFacts
+		sp.reg = NewRegistryFrom(blkFacts)
+	}
+}
+
+// Registry of block factories.
+// Default - global registry, see DefaultRegistry.
+func WithRegistry(reg *Registry) SputnikOption {
+	return func(sp *Sputnik) {
+		sp.reg = reg
 	}
 }
 
@@ -68,8 +107,86 @@ func WithConnector(cnt ServerConnector, to time.Duration) SputnikOption {
 	}
 }
 
+// Default deadline for Finish of every block during shutdown.
+// Block, which did not finish in time, is abandoned:
+// shutdown continues without it and Launch returns error with
+// list of abandoned blocks.
+// 0 (default) - wait without deadline.
+// BlockDescriptor.FinishTimeout overrides this value for specific block.
+func WithFinishTimeout(to time.Duration) SputnikOption {
+	return func(sp *Sputnik) {
+		sp.fto = to
+	}
+}
+
+// Reaction on recovered panic within callback of any block.
+// Default - PanicShutdown.
+func WithPanicPolicy(pp PanicPolicy) SputnikOption {
+	return func(sp *Sputnik) {
+		sp.pp = pp
+	}
+}
+
+// Opt-in parallel initialization of blocks.
+// Blocks without dependency relation are initialized concurrently,
+// number of concurrent Init calls is limited by workers.
+// If Init of any block failed, already initialized blocks are finished
+// in reverse order of initialization.
+// workers <= 1 - sequential initialization (default).
+func WithParallelInit(workers int) SputnikOption {
+	return func(sp *Sputnik) {
+		sp.workers = workers
+	}
+}
+
+// Watcher of configuration changes.
+// Blocks are notified about changes via OnConfigChange.
+func WithConfigWatcher(cw ConfigWatcher) SputnikOption {
+	return func(sp *Sputnik) {
+		sp.cw = cw
+	}
+}
+
+// Reaction on configuration change for blocks without OnConfigChange callback.
+// Default - ConfigChangeIgnore.
+func WithConfigChangePolicy(ccp ConfigChangePolicy) SputnikOption {
+	return func(sp *Sputnik) {
+		sp.ccp = ccp
+	}
+}
+
+// Observer of life cycle events of blocks and process
+func WithLifecycleObserver(obs LifecycleObserver) SputnikOption {
+	return func(sp *Sputnik) {
+		sp.obs = obs
+	}
+}
+
+// Dead-letter block receives every undeliverable message (see DeadLetter).
+// Use DeadLetterDescriptor() for default in-memory implementation,
+// set "spool" option of the descriptor for JSONL file spool.
+func WithDeadLetters(dlbd BlockDescriptor) SputnikOption {
+	return func(sp *Sputnik) {
+		sp.dlbd = &dlbd
+	}
+}
+
+// Middlewares around callbacks of every application block (see Middleware)
+func WithMiddleware(mws ...Middleware) SputnikOption {
+	return func(sp *Sputnik) {
+		sp.mws = append(sp.mws, mws...)
+	}
+}
+
+// Sink for tracing of messages sent between blocks
+func WithTraceSink(ts TraceSink) SputnikOption {
+	return func(sp *Sputnik) {
+		sp.ts = ts
+	}
+}
+
 func (sp *Sputnik) isValid() bool {
-	return sp.cnfFact != nil && sp.appBlocks != nil
+	return sp.cnfFact != nil && sp.appBlocks != nil && sp.reg != nil
 }
 
 func NewSputnik(opts ...SputnikOption) (*Sputnik, error) {
@@ -77,9 +194,9 @@ func NewSputnik(opts ...SputnikOption) (*Sputnik, error) {
 
 	// Pre-sets
 	WithFinisher(FinisherDescriptor())(sp)
-	WithBlockFactories(DefaultFactories())(sp)
+	WithRegistry(DefaultRegistry())(sp)
 
-	sp.cnd = BlockDescriptor{DefaultConnectorName, DefaultConnectorResponsibility}
+	sp.cnd = ConnectorDescriptor()
 
 	for _, opt := range opts {
 		opt(sp)
@@ -94,6 +211,8 @@ func NewSputnik(opts ...SputnikOption) (*Sputnik, error) {
 }
 
 // sputnik launcher
+// Returns nil after clean finish of the flight, otherwise *ExitError
+// with reason of the finish and suggested exit code of the process
 type Launch func() error
 
 // sputnik shooter
@@ -118,17 +237,74 @@ type ShootDown func()
 //   - st - ShootDown of sputnik - abort flight
 func (sputnik Sputnik) Prepare() (lfn Launch, st ShootDown, err error) {
 
+	fl, err := sputnik.PrepareContext(context.Background())
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fl.Launch, fl.ShootDown, nil
+}
+
+// Flight provides management of prepared sputnik
+type Flight struct {
+	inr *initiator
+}
+
+// Creates and initializes all blocks like Prepare.
+//
+// ctx controls the whole flight:
+//
+//   - cancel during initialization terminates it, already initialized
+//     blocks are finished in reverse order, ctx.Err() is returned
+//
+//   - cancel after Launch triggers the same ordered finish of blocks as FinishMsg
+func (sputnik Sputnik) PrepareContext(ctx context.Context) (*Flight, error) {
+
 	inr := new(initiator)
 
 	inr.sputnik = sputnik
+	inr.ctx = ctx
 
-	err = inr.init(nil)
+	err := inr.init(nil)
 
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return inr.runInternal, inr.abort, nil
+	return &Flight{inr}, nil
+}
+
+// Launch of the sputnik, exit from this function will be
+// after signal for shutdown of the process, after cancel of
+// the flight context or after call of ShootDown.
+// Returns nil after clean finish, *ExitError - after fatal error or panic
+// of the block, exceeded restarts or abandoned blocks.
+// See Exit for details of clean finish.
+func (fl *Flight) Launch() error {
+	return fl.inr.runInternal()
+}
+
+// Exit describes the end of the flight: reason, name of the signal,
+// errors of blocks collected during the flight and suggested exit code.
+// Unlike Launch, it is available also after clean finish (Code 0).
+// Returns nil before return from Launch.
+func (fl *Flight) Exit() *ExitError {
+	fl.inr.Lock()
+	defer fl.inr.Unlock()
+	return fl.inr.exit
+}
+
+// ShootDown of sputnik - abort flight and wait finish of all blocks
+func (fl *Flight) ShootDown() {
+	fl.inr.abort()
+}
+
+// ShootDownWithTimeout aborts flight and waits finish of all blocks
+// no longer than timeout.
+// Returned error contains list of blocks that did not finish in time.
+func (fl *Flight) ShootDownWithTimeout(to time.Duration) error {
+	return fl.inr.abortWithTimeout(to)
 }
 
 func (sputnik *Sputnik) createActiveBlocks() (activeBlocks, error) {
@@ -140,8 +316,17 @@ func (sputnik *Sputnik) createActiveBlocks() (activeBlocks, error) {
 		dscrs = append(dscrs, ConnectorDescriptor())
 	}
 
+	if sputnik.dlbd != nil {
+		dscrs = append(dscrs, *sputnik.dlbd)
+	}
+
 	dscrs = append(dscrs, sputnik.appBlocks...)
 
+	dscrs, err := sortByDependencies(dscrs)
+	if err != nil {
+		return nil, err
+	}
+
 	abls := make(activeBlocks, 0)
 
 	for _, bd := range dscrs {
@@ -155,18 +340,51 @@ func (sputnik *Sputnik) createActiveBlocks() (activeBlocks, error) {
 	return abls, nil
 }
 
+// Default dead-letter block is built-in, unless the registry
+// contains factory with the same name
+func (sputnik *Sputnik) createBlock(bd BlockDescriptor) (*Block, error) {
+	if bd.Name != DefaultDeadLetterName || sputnik.reg.exists(bd.Name) {
+		return sputnik.reg.createByDescr(&bd)
+	}
+
+	b, err := deadLetterBlockFactory(bd, bd.Options)
+	if err != nil {
+		return nil, fmt.Errorf("Creation of block [name: %s resp: %s] failed: %w", bd.Name, bd.Responsibility, err)
+	}
+	return b, nil
+}
+
+// Middlewares of sputnik (except infrastructure blocks) and of the block
+func (sputnik *Sputnik) middlewares(bd BlockDescriptor, b *Block) []Middleware {
+	resp := bd.Responsibility
+
+	infra := resp == sputnik.fbd.Responsibility ||
+		(sputnik.cnt != nil && resp == DefaultConnectorResponsibility) ||
+		(sputnik.dlbd != nil && resp == sputnik.dlbd.Responsibility)
+
+	if infra {
+		return b.mws
+	}
+
+	mws := make([]Middleware, 0, len(sputnik.mws)+len(b.mws))
+	mws = append(mws, sputnik.mws...)
+	return append(mws, b.mws...)
+}
+
 func (sputnik *Sputnik) createByDescr(bd BlockDescriptor) (*activeBlock, error) {
-	b, err := sputnik.blkFacts.createByDescr(&bd)
+	b, err := sputnik.createBlock(bd)
 
 	if err != nil {
 		return nil, err
 	}
 
+	b = wrapBlock(bd, b, sputnik.middlewares(bd, b))
+
 	if !b.isValid(sputnik.cnt != nil) {
 		return nil, fmt.Errorf("invalid callbacks in block: name =  %s resp = %s", bd.Name, bd.Responsibility)
 	}
 
-	abl := newActiveBlock(bd, b)
+	sputnik.observe(BlockCreated, bd, nil)
 
-	return &abl, nil
+	return newActiveBlock(bd, b), nil
 }
@@ -1,6 +1,17 @@
 package sputnik_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -61,6 +72,33 @@ func TestFinisher(t *testing.T) {
 	return
 }
 
+func TestExitSignal(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	fl := tb.fly(t)
+
+	if fl.Exit() != nil {
+		t.Errorf("exit should not be available before return from Launch")
+	}
+
+	// Simulate SIGQUIT
+	tb.sendTo("finisher", make(sputnik.Msg))
+
+	<-tb.done
+
+	if tb.err != nil {
+		t.Errorf("expected clean exit because of signal, got %v", tb.err)
+	}
+
+	ee := fl.Exit()
+	if ee == nil || ee.Reason != sputnik.ExitSignal || ee.Signal != syscall.SIGQUIT.String() || ee.Code != 0 {
+		t.Errorf("expected clean exit because of SIGQUIT, got %v", ee)
+	}
+
+	return
+}
+
 func TestRun(t *testing.T) {
 
 	tb := NewTestBlocks()
@@ -99,3 +137,2217 @@ func TestRun(t *testing.T) {
 
 	return
 }
+
+func TestLaunchContext(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tb.flyContext(t, ctx)
+
+	cancel()
+
+	select {
+	case <-tb.done:
+	case <-time.After(5 * time.Second):
+		t.Errorf("cancel of context did not finish the flight")
+	}
+
+	return
+}
+
+func TestShootDownWithTimeout(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	fl := tb.fly(t)
+
+	// Block "2" hangs within Finish
+	tb.block(1).hang = make(chan struct{})
+
+	err := fl.ShootDownWithTimeout(time.Second)
+
+	if err == nil || !strings.Contains(err.Error(), " 2]") {
+		t.Errorf("expected timeout error for block 2, got %v", err)
+	}
+
+	close(tb.block(1).hang)
+
+	<-tb.done
+
+	return
+}
+
+func TestFinishTimeout(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	tb.fly(t, sputnik.WithFinishTimeout(500*time.Millisecond))
+
+	// Block "2" hangs within Finish
+	tb.block(1).hang = make(chan struct{})
+	defer close(tb.block(1).hang)
+
+	tb.land()
+
+	if tb.err == nil || !strings.Contains(tb.err.Error(), ",2] exceeded deadline") {
+		t.Errorf("expected error for abandoned block 2, got %v", tb.err)
+	}
+
+	return
+}
+
+func TestPanicShutdown(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	tb.fly(t)
+
+	if !tb.sendTo("2", sputnik.Msg{"panic": true}) {
+		t.Fatalf("send to block 2 failed")
+	}
+
+	select {
+	case <-tb.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("panic did not shut down the process")
+	}
+
+	var ee *sputnik.ExitError
+	if !errors.As(tb.err, &ee) || ee.Reason != sputnik.ExitPanic || sputnik.ExitCode(tb.err) != 1 {
+		t.Errorf("expected exit because of panic, got %v", tb.err)
+	}
+
+	return
+}
+
+func TestPanicFinishBlock(t *testing.T) {
+
+	tb := NewTestBlocks()
+	obs := newEventsObserver()
+
+	fl := tb.fly(t, sputnik.WithPanicPolicy(sputnik.PanicFinishBlock), sputnik.WithLifecycleObserver(obs))
+
+	tb.sendTo("2", sputnik.Msg{"panic": true})
+
+	obs.wait(t, sputnik.FinishCompleted, "2")
+
+	if tb.sendTo("2", make(sputnik.Msg)) {
+		t.Errorf("block 2 should be finished after panic")
+	}
+
+	if !tb.sendTo("3", sputnik.Msg{"__name": "alive"}) || !tb.expect(1, "alive") {
+		t.Errorf("block 3 should continue to run")
+	}
+
+	tb.land()
+
+	if tb.err != nil {
+		t.Errorf("expected clean exit after ShootDown, got %v", tb.err)
+	}
+
+	// Errors of blocks are available also after clean finish
+	ee := fl.Exit()
+	var pe *sputnik.PanicError
+	if ee == nil || ee.Reason != sputnik.ExitShootDown || ee.Code != 0 ||
+		len(ee.BlockErrors) != 1 || !errors.As(ee.BlockErrors[0], &pe) {
+		t.Errorf("expected ShootDown with panic of block 2, got %v", ee)
+	}
+
+	return
+}
+
+func TestRestartOnFailure(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "dumb", Responsibility: "1"},
+		{Name: "dumb", Responsibility: "2", Restart: sputnik.RestartPolicy{
+			Mode:        sputnik.RestartOnFailure,
+			MaxRestarts: 3,
+			Backoff:     100 * time.Millisecond,
+		}},
+		{Name: "dumb", Responsibility: "3"},
+	}
+
+	tb.fly(t, sputnik.WithAppBlocks(blocks))
+
+	tb.sendTo("2", sputnik.Msg{"panic": true})
+
+	// Re-created block 2
+	tb.next(t).bc()
+
+	if tb.blocks() != 4 {
+		t.Errorf("expected re-creation of block 2, created %d blocks", tb.blocks())
+	}
+
+	if !tb.sendTo("2", make(sputnik.Msg)) {
+		t.Errorf("restarted block 2 should receive messages")
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestRestartAlways(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	// Run of the block returns without finish
+	quit := make(chan struct{}, 1)
+
+	reg := tb.registry()
+	reg.Register("quitter", func() *sputnik.Block {
+		dmb, blk := tb.dumb()
+		sputnik.WithRun(func(bc sputnik.BlockCommunicator) {
+			defer close(dmb.done)
+			select {
+			case <-dmb.stop:
+			case <-quit:
+			}
+		})(blk)
+		return blk
+	})
+
+	backoff := 200 * time.Millisecond
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "dumb", Responsibility: "1"},
+		{Name: "quitter", Responsibility: "q", Restart: sputnik.RestartPolicy{
+			Mode:    sputnik.RestartAlways,
+			Backoff: backoff,
+		}},
+	}
+
+	tb.fly(t, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks))
+
+	returned := time.Now()
+	quit <- struct{}{}
+
+	// Re-created block
+	tb.next(t).bc()
+
+	if elapsed := time.Since(returned); elapsed < backoff {
+		t.Errorf("block was re-created after %v, expected backoff %v", elapsed, backoff)
+	}
+
+	// Current connection is replayed to the new block
+	if !tb.expect(1, "serverConnected") {
+		t.Errorf("OnServerConnect was not called for restarted block")
+	}
+
+	if !tb.sendTo("q", sputnik.Msg{"__name": "alive"}) || !tb.expect(1, "alive") {
+		t.Errorf("restarted block should receive messages")
+	}
+
+	tb.land()
+
+	if tb.err != nil {
+		t.Errorf("expected clean exit, got %v", tb.err)
+	}
+
+	return
+}
+
+func TestRestartsExceeded(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	window := 200 * time.Millisecond
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "dumb", Responsibility: "1"},
+		{Name: "dumb", Responsibility: "2", Restart: sputnik.RestartPolicy{
+			Mode:        sputnik.RestartOnFailure,
+			MaxRestarts: 1,
+			Window:      window,
+		}},
+	}
+
+	tb.fly(t, sputnik.WithAppBlocks(blocks))
+
+	restart := func() {
+		t.Helper()
+		tb.sendTo("2", sputnik.Msg{"panic": true})
+		tb.next(t).bc()
+		if !tb.expect(1, "serverConnected") {
+			t.Fatalf("OnServerConnect was not called for restarted block")
+		}
+	}
+
+	restart()
+
+	// Restarts older than window are not counted
+	time.Sleep(window)
+	restart()
+
+	// The second restart within window exceeds the limit
+	tb.sendTo("2", sputnik.Msg{"panic": true})
+
+	select {
+	case <-tb.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("exceeded restarts did not shut down the process")
+	}
+
+	var ee *sputnik.ExitError
+	if !errors.As(tb.err, &ee) || ee.Reason != sputnik.ExitRestartsExceeded || ee.Code != 1 {
+		t.Errorf("expected exit because of exceeded restarts, got %v", tb.err)
+	}
+
+	return
+}
+
+func TestRestartSlowInit(t *testing.T) {
+
+	tb := NewTestBlocks()
+	jr := new(journal)
+	obs := newEventsObserver()
+
+	entered := make(chan struct{})
+	gate := make(chan struct{})
+
+	// Init of the restarted block hangs till close of gate
+	var inits atomic.Int32
+	reg := tb.registry()
+	tb.registerRecorder(reg, "slow", jr, func(resp string) error {
+		if inits.Add(1) == 2 {
+			close(entered)
+			<-gate
+		}
+		return nil
+	})
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "dumb", Responsibility: "1"},
+		{Name: "slow", Responsibility: "slow", Restart: sputnik.RestartPolicy{Mode: sputnik.RestartOnFailure}},
+	}
+
+	tb.fly(t, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks), sputnik.WithLifecycleObserver(obs))
+
+	tb.sendTo("slow", sputnik.Msg{"panic": true})
+
+	<-entered
+
+	// Init of the restarted block does not block initiator
+	mustReturn(t, "ShootDown", tb.land)
+
+	if tb.err != nil {
+		t.Errorf("expected clean exit, got %v", tb.err)
+	}
+
+	// Initialized instance is finished
+	close(gate)
+	obs.wait(t, sputnik.FinishCompleted, "slow") // failed instance
+	obs.wait(t, sputnik.FinishCompleted, "slow")
+
+	if jr.index("finish(true)>slow") < 0 {
+		t.Errorf("restarted block was not finished, journal %v", jr.list())
+	}
+
+	return
+}
+
+func TestDependencies(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		blocks []sputnik.BlockDescriptor
+		errMsg string
+	}{
+		{"missing", []sputnik.BlockDescriptor{
+			{Name: "dumb", Responsibility: "1", DependsOn: []string{"4"}},
+		}, "missing block 4"},
+		{"cycle", []sputnik.BlockDescriptor{
+			{Name: "dumb", Responsibility: "1", DependsOn: []string{"2"}},
+			{Name: "dumb", Responsibility: "2", DependsOn: []string{"1"}},
+			{Name: "dumb", Responsibility: "3"},
+		}, "cycle between blocks [1 2]"},
+	}
+
+	for _, tt := range tests {
+		tb := NewTestBlocks()
+
+		dsp := dumbSputnik(tb, sputnik.WithAppBlocks(tt.blocks))
+
+		_, _, err := dsp.Prepare()
+
+		if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("%s: expected error %q, got %v", tt.name, tt.errMsg, err)
+		}
+	}
+
+	// Valid dependencies: 3 <- 1 <- 2
+	tb := NewTestBlocks()
+	jr := new(journal)
+
+	reg := tb.registry()
+	tb.registerRecorder(reg, "rec", jr, nil)
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "rec", Responsibility: "1", DependsOn: []string{"3"}},
+		{Name: "rec", Responsibility: "2", DependsOn: []string{"1", sputnik.DefaultConnectorResponsibility}},
+		{Name: "rec", Responsibility: "3"},
+	}
+
+	tb.fly(t, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks))
+	tb.land()
+
+	if tb.err != nil {
+		t.Errorf("Launch error %v", tb.err)
+	}
+
+	// Init in dependency order
+	jr.before(t, "init<3", "init>1")
+	jr.before(t, "init<1", "init>2")
+
+	// Finish in reverse order
+	jr.before(t, "finish(false)<2", "finish(false)>1")
+	jr.before(t, "finish(false)<1", "finish(false)>3")
+
+	return
+}
+
+func TestParallelInit(t *testing.T) {
+
+	// Independent blocks: Init of every block waits start of Init of all blocks
+	tb := NewTestBlocks()
+	jr := new(journal)
+
+	reg := tb.registry()
+	tb.registerRecorder(reg, "rec", jr, initBarrier(3))
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "rec", Responsibility: "1"},
+		{Name: "rec", Responsibility: "2"},
+		{Name: "rec", Responsibility: "3"},
+	}
+
+	dsp := dumbSputnik(tb, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks), sputnik.WithParallelInit(3))
+
+	_, kill, err := dsp.Prepare()
+	if err != nil {
+		t.Fatalf("Prepare error %v", err)
+	}
+	kill()
+
+	return
+}
+
+func TestParallelInitDependencies(t *testing.T) {
+
+	// 1 <- (2, 3) <- 4, Init of 2 and 3 runs concurrently
+	tb := NewTestBlocks()
+	jr := new(journal)
+
+	barrier := initBarrier(2)
+
+	reg := tb.registry()
+	tb.registerRecorder(reg, "rec", jr, func(resp string) error {
+		if resp == "2" || resp == "3" {
+			return barrier(resp)
+		}
+		return nil
+	})
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "rec", Responsibility: "4", DependsOn: []string{"2", "3"}},
+		{Name: "rec", Responsibility: "3", DependsOn: []string{"1"}},
+		{Name: "rec", Responsibility: "2", DependsOn: []string{"1"}},
+		{Name: "rec", Responsibility: "1"},
+	}
+
+	dsp := dumbSputnik(tb, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks), sputnik.WithParallelInit(4))
+
+	_, kill, err := dsp.Prepare()
+	if err != nil {
+		t.Fatalf("Prepare error %v", err)
+	}
+	kill()
+
+	jr.before(t, "init<1", "init>2")
+	jr.before(t, "init<1", "init>3")
+	jr.before(t, "init<2", "init>4")
+	jr.before(t, "init<3", "init>4")
+
+	return
+}
+
+func TestParallelInitFailure(t *testing.T) {
+
+	// 1 <- (2, 4) <- 3, Init of 4 fails during Init of 2
+	tb := NewTestBlocks()
+	jr := new(journal)
+
+	started := make(chan struct{})
+	failed := make(chan struct{})
+
+	reg := tb.registry()
+	tb.registerRecorder(reg, "rec", jr, func(resp string) error {
+		switch resp {
+		case "2":
+			close(started)
+			<-failed
+		case "4":
+			<-started
+			close(failed)
+			return errors.New("init failed")
+		}
+		return nil
+	})
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "rec", Responsibility: "1"},
+		{Name: "rec", Responsibility: "2", DependsOn: []string{"1"}},
+		{Name: "rec", Responsibility: "3", DependsOn: []string{"2"}},
+		{Name: "rec", Responsibility: "4", DependsOn: []string{"1"}},
+	}
+
+	dsp := dumbSputnik(tb, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks), sputnik.WithParallelInit(2))
+
+	_, _, err := dsp.Prepare()
+	if err == nil || !strings.Contains(err.Error(), "init failed") {
+		t.Fatalf("expected init error, got %v", err)
+	}
+
+	// Init of 2 is completed, Init of 3 is not started after failure
+	if jr.index("init>3") >= 0 {
+		t.Errorf("init started after failure, journal %v", jr.list())
+	}
+
+	// Initialized blocks are finished in reverse order
+	jr.before(t, "finish(true)<2", "finish(true)>1")
+
+	for _, entry := range []string{"finish(true)>3", "finish(true)>4", "finish(false)>1", "finish(false)>2"} {
+		if jr.index(entry) >= 0 {
+			t.Errorf("unexpected %s, journal %v", entry, jr.list())
+		}
+	}
+
+	return
+}
+
+// Init of n blocks waits till Init of all of them was started
+func initBarrier(n int) func(resp string) error {
+	var wg sync.WaitGroup
+	wg.Add(n)
+
+	return func(resp string) error {
+		wg.Done()
+
+		all := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(all)
+		}()
+
+		select {
+		case <-all:
+			return nil
+		case <-time.After(5 * time.Second):
+			return fmt.Errorf("block %s was not initialized concurrently", resp)
+		}
+	}
+}
+
+func TestAddRemoveBlock(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	tb.fly(t)
+
+	bm, ok := tb.mainCntrl().(sputnik.BlocksManager)
+	if !ok {
+		t.Fatalf("initiator communicator does not support BlocksManager")
+	}
+
+	if err := bm.AddBlock(sputnik.BlockDescriptor{Name: "dumb", Responsibility: "4", DependsOn: []string{"1"}}); err != nil {
+		t.Errorf("AddBlock error %v", err)
+	}
+
+	if !tb.sendTo("4", make(sputnik.Msg)) {
+		t.Errorf("send to added block failed")
+	}
+
+	if err := bm.AddBlock(sputnik.BlockDescriptor{Name: "dumb", Responsibility: "1"}); err == nil {
+		t.Errorf("AddBlock of existing block should fail")
+	}
+
+	if err := bm.RemoveBlock("1"); err == nil {
+		t.Errorf("RemoveBlock of block with dependants should fail")
+	}
+
+	if err := bm.RemoveBlock(sputnik.DefaultFinisherResponsibility); err == nil {
+		t.Errorf("RemoveBlock of finisher should fail")
+	}
+
+	if err := bm.RemoveBlock("4"); err != nil {
+		t.Errorf("RemoveBlock error %v", err)
+	}
+
+	if _, exists := tb.comm("4"); exists {
+		t.Errorf("removed block still exists")
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestAddBlockSlowInit(t *testing.T) {
+
+	tb := NewTestBlocks()
+	jr := new(journal)
+	obs := newEventsObserver()
+
+	entered := make(chan struct{})
+	gate := make(chan struct{})
+
+	reg := tb.registry()
+	tb.registerRecorder(reg, "slow", jr, func(resp string) error {
+		close(entered)
+		<-gate
+		return nil
+	})
+
+	tb.fly(t, sputnik.WithRegistry(reg), sputnik.WithLifecycleObserver(obs))
+
+	bm, _ := tb.mainCntrl().(sputnik.BlocksManager)
+
+	added := make(chan error, 1)
+	go func() {
+		added <- bm.AddBlock(sputnik.BlockDescriptor{Name: "slow", Responsibility: "slow"})
+	}()
+
+	<-entered
+
+	// Init of the added block does not block initiator
+	if err := bm.AddBlock(sputnik.BlockDescriptor{Name: "slow", Responsibility: "slow"}); err == nil {
+		t.Errorf("AddBlock of initializing block should fail")
+	}
+
+	if err := bm.RemoveBlock("3"); err != nil {
+		t.Errorf("RemoveBlock error %v", err)
+	}
+
+	// Process finishes during Init, AddBlock returns error
+	tb.land()
+
+	select {
+	case err := <-added:
+		if err == nil {
+			t.Errorf("AddBlock should fail after finish of the process")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("AddBlock hangs after finish of the process")
+	}
+
+	// Initialized block is finished
+	close(gate)
+	obs.wait(t, sputnik.FinishCompleted, "slow")
+
+	if jr.index("finish(true)>slow") < 0 {
+		t.Errorf("block was not finished, journal %v", jr.list())
+	}
+
+	return
+}
+
+func TestConfigChange(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	tb.fly(t)
+
+	tb.mainCntrl().Send(sputnik.ConfigChangedMsg("unknown"))
+	tb.mainCntrl().Send(sputnik.ConfigChangedMsg("dumb"))
+
+	if !tb.expect(3, "configChanged") {
+		t.Errorf("Wrong processing of configChanged")
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestConfigChangeSIGHUP(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	tb.fly(t)
+
+	// Finisher catches signals after readiness of the process
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("Kill error %v", err)
+	}
+
+	if !tb.expect(3, "configChanged") {
+		t.Errorf("SIGHUP should be reported as change of all configurations")
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestConfigChangeRestart(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	reg := tb.registry()
+	tb.register(reg, "deaf", sputnik.WithOnConfigChange(nil))
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "dumb", Responsibility: "1"},
+		{Name: "deaf", Responsibility: "2"},
+	}
+
+	tb.fly(t, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks), sputnik.WithConfigChangePolicy(sputnik.ConfigChangeRestart))
+
+	old := tb.block(0)
+	if old.bc().Descriptor().Responsibility != "2" {
+		old = tb.block(1)
+	}
+
+	tb.mainCntrl().Send(sputnik.ConfigChangedMsg("dumb"))
+
+	// Block with OnConfigChange is notified, block without it is restarted
+	if !tb.expect(1, "configChanged") {
+		t.Errorf("Wrong processing of configChanged")
+	}
+
+	restarted := tb.next(t)
+	if restarted == old {
+		t.Fatalf("block without OnConfigChange should be re-created")
+	}
+
+	select {
+	case <-old.done:
+	default:
+		t.Errorf("old instance should be finished before restart")
+	}
+
+	// Connected server is reported to the new instance
+	if !tb.expect(1, "serverConnected") {
+		t.Errorf("restarted block should be connected")
+	}
+
+	if !tb.sendTo("2", sputnik.Msg{"__name": "after"}) || !tb.expect(1, "after") {
+		t.Errorf("restarted block should receive messages")
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestWaitReady(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	reg := tb.registry()
+	tb.register(reg, "ndumb", sputnik.WithReadyNotice())
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "dumb", Responsibility: "1"},
+		{Name: "dumb", Responsibility: "2"},
+		{Name: "ndumb", Responsibility: "3"},
+	}
+
+	dsp := dumbSputnik(tb, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks))
+
+	fl, err := dsp.PrepareContext(context.Background())
+
+	if err != nil {
+		t.Fatalf("PrepareContext error %v", err)
+	}
+
+	tb.attachQueue()
+	tb.launch = fl.Launch
+
+	tb.run()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	// Server is not connected yet
+	if err = fl.WaitReady(ctx); err == nil {
+		t.Errorf("process should not be ready before connection to server")
+	}
+
+	tb.conntr.SetState(true)
+
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel2()
+
+	if err = fl.WaitReady(ctx2); err != nil {
+		t.Errorf("WaitReady error %v", err)
+	}
+
+	// 3 x serverConnected and allReady only for block "3"
+	names := make(map[any]int)
+	for i := 0; i < 4; i++ {
+		msg, _ := tb.q.Get()
+		names[msg["__name"]]++
+	}
+
+	if names["serverConnected"] != 3 || names[sputnik.AllReadyMsgName] != 1 {
+		t.Errorf("unexpected messages %v", names)
+	}
+
+	fl.ShootDown()
+
+	<-tb.done
+
+	return
+}
+
+type testObserver struct {
+	sync.Mutex
+	kinds map[sputnik.LifecycleEventKind]int
+}
+
+func (to *testObserver) Observe(ev sputnik.LifecycleEvent) {
+	to.Lock()
+	defer to.Unlock()
+	to.kinds[ev.Kind]++
+}
+
+func (to *testObserver) count(kind sputnik.LifecycleEventKind) int {
+	to.Lock()
+	defer to.Unlock()
+	return to.kinds[kind]
+}
+
+func TestLifecycleObserver(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	obs := &testObserver{kinds: make(map[sputnik.LifecycleEventKind]int)}
+
+	tb.fly(t, sputnik.WithLifecycleObserver(obs))
+
+	tb.land()
+
+	// finisher, connector and 3 application blocks
+	for _, kind := range []sputnik.LifecycleEventKind{
+		sputnik.BlockCreated,
+		sputnik.InitStarted,
+		sputnik.InitSucceeded,
+		sputnik.RunStarted,
+		sputnik.FinishRequested,
+		sputnik.FinishCompleted,
+	} {
+		if obs.count(kind) != 5 {
+			t.Errorf("expected 5 events %s, got %d", kind, obs.count(kind))
+		}
+	}
+
+	if obs.count(sputnik.ProcessExit) != 1 {
+		t.Errorf("expected process exit event")
+	}
+
+	return
+}
+
+func TestRegistry(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	reg := sputnik.DefaultRegistry().Clone()
+
+	if err := reg.Register("dumb", tb.dbFact); err != nil {
+		t.Fatalf("Register error %v", err)
+	}
+
+	if err := reg.Register("dumb", tb.dbFact); err == nil {
+		t.Errorf("duplicated registration should fail")
+	}
+
+	if err := reg.Override("dumb", tb.dbFact); err != nil {
+		t.Errorf("Override error %v", err)
+	}
+
+	if _, err := sputnik.Factory("dumb"); err == nil {
+		t.Errorf("global registry should not be changed")
+	}
+
+	names := strings.Join(reg.List(), ",")
+	if names != "connector,dumb,finisher" {
+		t.Errorf("unexpected list of factories %s", names)
+	}
+
+	dsp := dumbSputnik(tb, sputnik.WithRegistry(reg))
+
+	_, kill, err := dsp.Prepare()
+
+	if err != nil {
+		t.Fatalf("Prepare error %v", err)
+	}
+
+	kill()
+
+	if !reg.Unregister("dumb") || reg.Unregister("dumb") {
+		t.Errorf("wrong unregistration")
+	}
+
+	// DefaultFactories returns factories of global registry, not a copy
+	facts := sputnik.DefaultFactories()
+	if err := sputnik.RegisterBlockFactoryInner("legacy", tb.dbFact, facts); err != nil {
+		t.Fatalf("RegisterBlockFactoryInner error %v", err)
+	}
+	defer delete(facts, "legacy")
+
+	if _, err := sputnik.Factory("legacy"); err != nil {
+		t.Errorf("factory added to DefaultFactories should be used by global registry, got %v", err)
+	}
+
+	return
+}
+
+func TestParamFactory(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	type dumbOptions struct {
+		Delay string `json:"delay"`
+	}
+
+	var created []string
+	reg := sputnik.NewRegistryFrom(tb.factories())
+
+	reg.RegisterParam("pdumb", func(bd sputnik.BlockDescriptor, opts sputnik.BlockOptions) (*sputnik.Block, error) {
+		var do dumbOptions
+		if err := opts.Decode(&do); err != nil {
+			return nil, err
+		}
+		if do.Delay == "never" {
+			return nil, errors.New("unsupported delay")
+		}
+		created = append(created, bd.Responsibility+":"+do.Delay)
+		return tb.dbFact(), nil
+	})
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "pdumb", Responsibility: "1", Options: sputnik.BlockOptions{"delay": "1s"}},
+		{Name: "pdumb", Responsibility: "2"},
+	}
+
+	dsp := dumbSputnik(tb, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks))
+
+	_, kill, err := dsp.Prepare()
+	if err != nil {
+		t.Fatalf("Prepare error %v", err)
+	}
+	kill()
+
+	if strings.Join(created, ",") != "1:1s,2:" {
+		t.Errorf("unexpected created blocks %v", created)
+	}
+
+	blocks[1].Options = sputnik.BlockOptions{"delay": "never"}
+
+	dsp = dumbSputnik(tb, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks))
+
+	_, _, err = dsp.Prepare()
+	if err == nil || !strings.Contains(err.Error(), "unsupported delay") {
+		t.Errorf("expected error of factory, got %v", err)
+	}
+
+	return
+}
+
+// Interface-based block
+type typedBlock struct {
+	Greeting string `json:"greeting"`
+	stop     chan struct{}
+}
+
+var typedGreetings = make(chan string, 2)
+
+func (tbl *typedBlock) Init(cf sputnik.ConfFactory) error {
+	tbl.stop = make(chan struct{})
+	return nil
+}
+
+func (tbl *typedBlock) Run(bc sputnik.BlockCommunicator) {
+	typedGreetings <- tbl.Greeting
+	<-tbl.stop
+}
+
+func (tbl *typedBlock) Finish(init bool) {
+	close(tbl.stop)
+}
+
+func TestBlockFrom(t *testing.T) {
+
+	if _, err := sputnik.BlockFrom(new(dumbBlock)); err == nil || !strings.Contains(err.Error(), "Init|Run|Finish") {
+		t.Errorf("expected error for object without methods, got %v", err)
+	}
+
+	if blk, err := sputnik.BlockFrom(new(typedBlock)); err != nil || blk == nil {
+		t.Errorf("BlockFrom error %v", err)
+	}
+
+	tb := NewTestBlocks()
+	reg := sputnik.NewRegistryFrom(tb.factories())
+
+	if err := sputnik.RegisterType[typedBlock](reg, "typed"); err != nil {
+		t.Fatalf("RegisterType error %v", err)
+	}
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "typed", Responsibility: "hello", Options: sputnik.BlockOptions{"greeting": "hello"}},
+		{Name: "typed", Responsibility: "bye", Options: sputnik.BlockOptions{"greeting": "bye"}},
+	}
+
+	dsp := dumbSputnik(tb, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks))
+
+	var err error
+	tb.launch, tb.kill, err = dsp.Prepare()
+	if err != nil {
+		t.Fatalf("Prepare error %v", err)
+	}
+
+	tb.run()
+
+	got := []string{<-typedGreetings, <-typedGreetings}
+	sort.Strings(got)
+
+	if strings.Join(got, ",") != "bye,hello" {
+		t.Errorf("unexpected greetings %v", got)
+	}
+
+	tb.kill()
+	<-tb.done
+
+	return
+}
+
+func TestAsk(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	tb.fly(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	bc2, _ := tb.comm("2")
+
+	resp, err := bc2.Ask(ctx, sputnik.Msg{"ask": "ping"})
+	if err != nil || resp["echo"] != "ping" {
+		t.Errorf("unexpected reply %v error %v", resp, err)
+	}
+
+	// Block does not reply
+	sctx, scancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer scancel()
+
+	if _, err = bc2.Ask(sctx, sputnik.Msg{"silent": true}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+
+	bc3, _ := tb.comm("3")
+
+	bm, _ := tb.mainCntrl().(sputnik.BlocksManager)
+	if err = bm.RemoveBlock("3"); err != nil {
+		t.Fatalf("RemoveBlock error %v", err)
+	}
+
+	if _, err = bc3.Ask(ctx, sputnik.Msg{"ask": "ping"}); !errors.Is(err, sputnik.ErrBlockFinished) {
+		t.Errorf("expected ErrBlockFinished, got %v", err)
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestPublish(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	tb.fly(t)
+
+	for _, resp := range []string{"1", "2"} {
+		bc, _ := tb.comm(resp)
+		if !bc.Subscribe("news") {
+			t.Fatalf("Subscribe of block %s failed", resp)
+		}
+	}
+
+	bc3, _ := tb.comm("3")
+
+	if n := bc3.Publish("news", sputnik.Msg{"__name": "news"}); n != 2 {
+		t.Errorf("expected 2 recipients, got %d", n)
+	}
+
+	if !tb.expect(2, "news") {
+		t.Errorf("Wrong processing of published message")
+	}
+
+	if n := bc3.Publish("weather", sputnik.Msg{"__name": "weather"}); n != 0 {
+		t.Errorf("expected 0 recipients, got %d", n)
+	}
+
+	bm, _ := tb.mainCntrl().(sputnik.BlocksManager)
+	if err := bm.RemoveBlock("2"); err != nil {
+		t.Fatalf("RemoveBlock error %v", err)
+	}
+
+	if n := bc3.Publish("news", sputnik.Msg{"__name": "news"}); n != 1 {
+		t.Errorf("subscription of finished block should be removed, got %d recipients", n)
+	}
+
+	tb.land()
+
+	return
+}
+
+// Sends message, which blocks OnMsg of the block till close of gate.
+// Returns after start of processing.
+func holdOnMsg(bc sputnik.BlockCommunicator, name string, gate chan struct{}) {
+	entered := make(chan struct{})
+	bc.Send(sputnik.Msg{"__name": name, "entered": entered, "wait": gate})
+	<-entered
+}
+
+func TestMailbox(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	reg := tb.registry()
+	tb.register(reg, "reject", sputnik.WithMailbox(2, sputnik.OverflowReject))
+	tb.register(reg, "oldest", sputnik.WithMailbox(2, sputnik.OverflowDropOldest))
+	tb.register(reg, "newest", sputnik.WithMailbox(2, sputnik.OverflowDropNewest))
+	tb.register(reg, "block", sputnik.WithMailbox(2, sputnik.OverflowBlock))
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "reject", Responsibility: "reject"},
+		{Name: "oldest", Responsibility: "oldest"},
+		{Name: "newest", Responsibility: "newest"},
+		{Name: "block", Responsibility: "block"},
+	}
+
+	tb.fly(t, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Fill mailboxes: first message blocks OnMsg, next 2 messages are queued
+	gate := make(chan struct{})
+	bcs := make(map[string]sputnik.BlockCommunicator)
+
+	for _, bd := range blocks {
+		bc, _ := tb.comm(bd.Responsibility)
+		bcs[bd.Responsibility] = bc
+
+		if bd.Responsibility == "newest" {
+			continue
+		}
+
+		holdOnMsg(bc, "gate", gate)
+		bc.Send(make(sputnik.Msg))
+		bc.Send(make(sputnik.Msg))
+	}
+
+	if bcs["reject"].Send(make(sputnik.Msg)) {
+		t.Errorf("message to full mailbox should be rejected")
+	}
+	if err := bcs["reject"].SendContext(ctx, make(sputnik.Msg)); !errors.Is(err, sputnik.ErrMailboxFull) {
+		t.Errorf("expected ErrMailboxFull, got %v", err)
+	}
+	if st := bcs["reject"].Mailbox(); st.Rejected != 2 || st.Len != 2 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+
+	if !bcs["oldest"].Send(make(sputnik.Msg)) {
+		t.Errorf("message should replace the oldest one")
+	}
+	if st := bcs["oldest"].Mailbox(); st.Dropped != 1 || st.Len != 2 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+
+	// Other blocks are held, the test receives only messages of "newest"
+	ngate := make(chan struct{})
+	holdOnMsg(bcs["newest"], "held", ngate)
+	bcs["newest"].Send(sputnik.Msg{"__name": "first"})
+	bcs["newest"].Send(sputnik.Msg{"__name": "second"})
+
+	if !bcs["newest"].Send(sputnik.Msg{"__name": "third"}) {
+		t.Errorf("the newest message should be dropped silently")
+	}
+	if st := bcs["newest"].Mailbox(); st.Dropped != 1 || st.Len != 2 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+
+	close(ngate)
+	if !tb.expect(1, "held") || !tb.expect(1, "first") || !tb.expect(1, "second") {
+		t.Errorf("queued messages should survive, the newest one should be dropped")
+	}
+	if st := bcs["newest"].Mailbox(); st.Dropped != 1 || st.Len != 0 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+
+	sctx, scancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer scancel()
+
+	if err := bcs["block"].SendContext(sctx, make(sputnik.Msg)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+
+	close(gate)
+
+	if err := bcs["block"].SendContext(ctx, make(sputnik.Msg)); err != nil {
+		t.Errorf("SendContext error %v", err)
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestPriority(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	tb.fly(t)
+
+	bc, _ := tb.comm("2")
+
+	gate := make(chan struct{})
+	holdOnMsg(bc, "gate", gate)
+
+	for i := 0; i < 3; i++ {
+		bc.Send(sputnik.Msg{"__name": "normal"})
+	}
+	bc.SendWithPriority(sputnik.Msg{"__name": "urgent"}, sputnik.PriorityHigh)
+
+	close(gate)
+
+	if !tb.expect(1, "gate") || !tb.expect(1, "urgent") || !tb.expect(3, "normal") {
+		t.Errorf("high priority message should be processed first")
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestTimers(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	tb.fly(t)
+
+	bc, _ := tb.comm("2")
+
+	if _, err := bc.SendCron("61 * * * *", make(sputnik.Msg)); err == nil {
+		t.Errorf("expected error for wrong cron expression")
+	}
+
+	if _, err := bc.SendCron("*/5 9-17 * * 1-5", make(sputnik.Msg)); err != nil {
+		t.Errorf("SendCron error %v", err)
+	}
+
+	start := time.Now()
+	bc.SendAfter(100*time.Millisecond, sputnik.Msg{"__name": "after"})
+
+	if !tb.expect(1, "after") || time.Since(start) < 100*time.Millisecond {
+		t.Errorf("Wrong processing of SendAfter")
+	}
+
+	// Cancelled by finish of the block
+	bc.SendEvery(20*time.Millisecond, sputnik.Msg{"__name": "tick"})
+
+	if !tb.expect(3, "tick") {
+		t.Errorf("Wrong processing of SendEvery")
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestTimersStopOnFinish(t *testing.T) {
+
+	tb := NewTestBlocks()
+	sink := new(testSink)
+
+	tb.fly(t, sputnik.WithTraceSink(sink))
+
+	bc, _ := tb.comm("3")
+
+	bc.SendEvery(5*time.Millisecond, sputnik.Msg{"__name": "tick"})
+	if _, err := bc.SendCron("* * * * *", sputnik.Msg{"__name": "cron"}); err != nil {
+		t.Errorf("SendCron error %v", err)
+	}
+
+	if !tb.expect(2, "tick") {
+		t.Errorf("Wrong processing of SendEvery")
+	}
+
+	bm, _ := tb.mainCntrl().(sputnik.BlocksManager)
+	if err := bm.RemoveBlock("3"); err != nil {
+		t.Fatalf("RemoveBlock error %v", err)
+	}
+
+	// RemoveBlock returns after finish of the block, timers are already stopped
+	hops := func() int {
+		sink.lock.Lock()
+		defer sink.lock.Unlock()
+
+		result := 0
+		for _, hop := range sink.hops {
+			if hop.To == "3" {
+				result++
+			}
+		}
+		return result
+	}
+
+	finished := hops()
+
+	// Ticks of running block are used as clock
+	bc2, _ := tb.comm("2")
+	cancel := bc2.SendEvery(5*time.Millisecond, sputnik.Msg{"__name": "clock"})
+	for clocks := 0; clocks < 3; {
+		if tb.receive(t)["__name"] == "clock" {
+			clocks++
+		}
+	}
+	cancel()
+
+	if after := hops(); after != finished {
+		t.Errorf("timers of finished block are running, %d messages were sent after finish", after-finished)
+	}
+
+	tb.land()
+
+	return
+}
+
+type alert struct {
+	Level int
+}
+
+func TestTypedMsg(t *testing.T) {
+
+	tb := NewTestBlocks()
+	reg := tb.registry()
+
+	if _, err := sputnik.NewTypedSender[alert](nil); !errors.Is(err, sputnik.ErrUnknownMsgType) {
+		t.Errorf("expected error for not registered type, got %v", err)
+	}
+
+	if err := sputnik.RegisterMsgType[alert](reg, "alert"); err != nil {
+		t.Fatalf("RegisterMsgType error %v", err)
+	}
+
+	if err := sputnik.RegisterMsgType[string](reg, "alert"); err == nil {
+		t.Errorf("expected error for duplicated name")
+	}
+
+	for _, name := range []string{"__alert", sputnik.AllReadyMsgName, sputnik.DeadLetterMsgName, "finished"} {
+		if err := sputnik.RegisterMsgType[int](reg, name); err == nil {
+			t.Errorf("expected error for reserved name %s", name)
+		}
+	}
+
+	// Registration is not global
+	if _, err := sputnik.MsgTypeName[alert](sputnik.DefaultRegistry()); !errors.Is(err, sputnik.ErrUnknownMsgType) {
+		t.Errorf("type is registered in global registry")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("MustRegisterMsgType should panic for reserved name")
+			}
+		}()
+		sputnik.MustRegisterMsgType[int]("__int")
+	}()
+
+	md := sputnik.NewMsgDispatcher()
+	md.UseRegistry(reg)
+
+	var level int
+	sputnik.HandleTyped(md, func(a alert) { level = a.Level })
+
+	var plain sputnik.Msg
+	md.HandlePlain(func(msg sputnik.Msg) { plain = msg })
+
+	var unknown error
+	md.OnUnknown(func(msg sputnik.Msg, err error) { unknown = err })
+
+	am, _ := sputnik.TypedMsg(reg, alert{Level: 3})
+	md.OnMsg(am)
+	if level != 3 {
+		t.Errorf("typed handler was not called")
+	}
+
+	md.OnMsg(sputnik.Msg{"key": "value"})
+	if plain["key"] != "value" {
+		t.Errorf("plain handler was not called")
+	}
+
+	md.OnMsg(sputnik.Msg{"__name": "alert", "__payload": "wrong"})
+	if !errors.Is(unknown, sputnik.ErrUnknownMsgType) {
+		t.Errorf("expected report of wrong payload, got %v", unknown)
+	}
+
+	// Payload of registered type with name of another type
+	if err := sputnik.RegisterMsgType[int](reg, "count"); err != nil {
+		t.Fatalf("RegisterMsgType error %v", err)
+	}
+
+	level = 0
+	if err := md.Dispatch(sputnik.Msg{"__name": "count", "__payload": alert{Level: 7}}); !errors.Is(err, sputnik.ErrUnknownMsgType) || level != 0 {
+		t.Errorf("expected rejection of payload with wrong name, got %v", err)
+	}
+
+	if err := md.Dispatch(sputnik.Msg{"__name": "unregistered", "__payload": alert{Level: 7}}); !errors.Is(err, sputnik.ErrUnknownMsgType) || level != 0 {
+		t.Errorf("expected rejection of not registered name, got %v", err)
+	}
+
+	// Registry of the dispatcher
+	gmd := sputnik.NewMsgDispatcher()
+	sputnik.HandleTyped(gmd, func(a alert) { level = a.Level })
+	if err := gmd.Dispatch(am); !errors.Is(err, sputnik.ErrUnknownMsgType) || level != 0 {
+		t.Errorf("name is not registered in global registry, got %v", err)
+	}
+
+	// Typed message to plain block, type is registered in registry of the process
+	tb.fly(t, sputnik.WithRegistry(reg))
+
+	bc, _ := tb.comm("1")
+
+	as, err := sputnik.NewTypedSender[alert](bc)
+	if err != nil {
+		t.Fatalf("NewTypedSender error %v", err)
+	}
+
+	if !as.Send(alert{Level: 5}) || !tb.expect(1, "alert") {
+		t.Errorf("Wrong processing of typed message")
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestBroadcast(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	tb.fly(t)
+
+	bc := tb.block(0).bc()
+
+	res := bc.Broadcast(sputnik.Msg{"__name": "flush"}, sputnik.BroadcastFilter{})
+	if len(res) != 3 || !res["1"] || !res["2"] || !res["3"] {
+		t.Errorf("unexpected result of broadcast %v", res)
+	}
+
+	if !tb.expect(3, "flush") {
+		t.Errorf("Wrong processing of broadcast")
+	}
+
+	res = bc.Broadcast(sputnik.Msg{"__name": "rotate"}, sputnik.BroadcastFilter{Name: "dumb", RespPrefix: "2"})
+	if len(res) != 1 || !res["2"] || !tb.expect(1, "rotate") {
+		t.Errorf("unexpected result of filtered broadcast %v", res)
+	}
+
+	if res = bc.Broadcast(sputnik.Msg{}, sputnik.BroadcastFilter{Name: "syslogreceiver"}); len(res) != 0 {
+		t.Errorf("unexpected recipients %v", res)
+	}
+
+	tb.land()
+
+	return
+}
+
+type testSink struct {
+	lock sync.Mutex
+	hops []sputnik.TraceHop
+}
+
+func (ts *testSink) Trace(hop sputnik.TraceHop) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ts.hops = append(ts.hops, hop)
+}
+
+func (ts *testSink) chain(correlation string) []sputnik.TraceHop {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+
+	var result []sputnik.TraceHop
+	for _, hop := range ts.hops {
+		if hop.Correlation == correlation {
+			result = append(result, hop)
+		}
+	}
+	return result
+}
+
+func TestTracing(t *testing.T) {
+
+	tb := NewTestBlocks()
+	sink := new(testSink)
+
+	tb.fly(t, sputnik.WithTraceSink(sink))
+
+	// 1 -> 2 -> 3 -> test
+	bc2, _ := tb.comm("2")
+	bc2.Send(sputnik.Msg{"__name": "hop", "forward": "3"})
+
+	msg, _ := tb.q.Get()
+
+	if msg["__name"] != "hop" || msg["__from"] != "2" {
+		t.Fatalf("unexpected message %v", msg)
+	}
+
+	correlation, _ := msg["__correlation"].(string)
+
+	hops := sink.chain(correlation)
+
+	if len(hops) != 2 {
+		t.Fatalf("expected 2 hops, got %v", hops)
+	}
+
+	if hops[0].From != "1" || hops[0].To != "2" || hops[0].ID != correlation || hops[0].Causation != "" {
+		t.Errorf("unexpected first hop %+v", hops[0])
+	}
+
+	if hops[1].From != "2" || hops[1].To != "3" || hops[1].Causation != hops[0].ID || !hops[1].Delivered {
+		t.Errorf("unexpected second hop %+v", hops[1])
+	}
+
+	// Forward on goroutine started by OnMsg
+	bc2.Send(sputnik.Msg{"__name": "async", "forward": "3", "async": true})
+
+	msg, _ = tb.q.Get()
+
+	correlation, _ = msg["__correlation"].(string)
+	if hops = sink.chain(correlation); len(hops) != 2 || hops[1].Causation != hops[0].ID || msg["__name"] != "async" {
+		t.Errorf("unexpected chain of message sent via CausedBy %+v", hops)
+	}
+
+	// Send of the block outside of OnMsg (e.g. from Run) during processing of message
+	gate := make(chan struct{})
+	holdOnMsg(bc2, "gate", gate)
+
+	own3, _ := tb.block(1).bc().Communicator("3")
+	own3.Send(sputnik.Msg{"__name": "run"})
+
+	msg, _ = tb.q.Get()
+	if _, caused := msg["__causation"]; msg["__name"] != "run" || caused || msg["__correlation"] != msg["__id"] {
+		t.Errorf("message sent outside of OnMsg should start new chain, got %v", msg)
+	}
+
+	// Timer of another block is sent by the block, which set it
+	own3.SendAfter(time.Millisecond, sputnik.Msg{"__name": "timer"})
+
+	msg, _ = tb.q.Get()
+	if msg["__name"] != "timer" || msg["__from"] != "2" {
+		t.Errorf("unexpected sender of timer message %v", msg)
+	}
+
+	close(gate)
+	tb.expect(1, "gate")
+
+	tb.land()
+
+	return
+}
+
+func TestDeadLetters(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	reg := tb.registry()
+
+	spool := filepath.Join(t.TempDir(), "deadletters.jsonl")
+
+	dlbd := sputnik.DeadLetterDescriptor()
+	dlbd.Options = sputnik.BlockOptions{"spool": spool}
+
+	tb.fly(t, sputnik.WithRegistry(reg), sputnik.WithDeadLetters(dlbd))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	bc3, _ := tb.comm("3")
+	dlbc, _ := tb.comm(sputnik.DefaultDeadLetterResponsibility)
+
+	bm, _ := tb.mainCntrl().(sputnik.BlocksManager)
+	if err := bm.RemoveBlock("3"); err != nil {
+		t.Fatalf("RemoveBlock error %v", err)
+	}
+
+	if bc3.Send(sputnik.Msg{"__name": "lost"}) {
+		t.Fatalf("send to removed block should fail")
+	}
+
+	letters, err := sputnik.ListDeadLetters(ctx, dlbc)
+	if err != nil || len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %v error %v", letters, err)
+	}
+
+	// Without trace sink the sender is unknown
+	if dl := letters[0]; dl.To != "3" || dl.From != "" || !strings.Contains(dl.Reason, "finished") || dl.Msg["__name"] != "lost" {
+		t.Errorf("unexpected dead letter %+v", dl)
+	}
+
+	spooled, err := sputnik.ReadDeadLetterSpool(spool)
+	if err != nil || len(spooled) != 1 || spooled[0].To != "3" {
+		t.Errorf("unexpected spool %v error %v", spooled, err)
+	}
+
+	// Recipient does not exist - letter is kept
+	if n, _ := sputnik.ReplayDeadLetters(ctx, dlbc); n != 0 {
+		t.Errorf("expected 0 replayed messages, got %d", n)
+	}
+
+	if err = bm.AddBlock(sputnik.BlockDescriptor{Name: "dumb", Responsibility: "3"}); err != nil {
+		t.Fatalf("AddBlock error %v", err)
+	}
+
+	if n, _ := sputnik.ReplayDeadLetters(ctx, dlbc); n != 1 {
+		t.Errorf("expected 1 replayed message, got %d", n)
+	}
+
+	// Skip OnServerConnect of added block
+	msg, _ := tb.q.Get()
+	for msg["__name"] == "serverConnected" {
+		msg, _ = tb.q.Get()
+	}
+
+	if msg["__name"] != "lost" {
+		t.Errorf("replayed message was not received, got %v", msg)
+	}
+
+	// Spool of messages with values without JSON representation
+	bc2, _ := tb.comm("2")
+	if err = bm.RemoveBlock("2"); err != nil {
+		t.Fatalf("RemoveBlock error %v", err)
+	}
+
+	if _, err = bc2.Ask(ctx, sputnik.Msg{"__name": "question"}); err == nil {
+		t.Errorf("Ask of removed block should fail")
+	}
+
+	if _, err = sputnik.ListDeadLetters(ctx, dlbc); err != nil {
+		t.Errorf("unexpected spool error %v", err)
+	}
+
+	bc2.Send(sputnik.Msg{"__name": "bad", "fn": func() {}})
+
+	if letters, err = sputnik.ListDeadLetters(ctx, dlbc); !errors.Is(err, sputnik.ErrDeadLetterSpool) || len(letters) != 2 {
+		t.Errorf("expected spool error, got %v error %v", letters, err)
+	}
+
+	spooled, err = sputnik.ReadDeadLetterSpool(spool)
+	if err != nil || len(spooled) != 3 {
+		t.Fatalf("unexpected spool %v error %v", spooled, err)
+	}
+
+	if _, exists := spooled[1].Msg["__reply"]; exists || spooled[1].Msg["__name"] != "question" {
+		t.Errorf("unexpected spooled request %+v", spooled[1])
+	}
+
+	if spooled[2].Msg != nil || !strings.Contains(spooled[2].Reason, "not spooled") {
+		t.Errorf("unexpected spooled message %+v", spooled[2])
+	}
+
+	// Letter without message is not replayed
+	bc1, _ := tb.comm("1")
+	bc1.Send(nil)
+
+	if n, _ := sputnik.ReplayDeadLetters(ctx, dlbc); n != 0 {
+		t.Errorf("expected 0 replayed messages, got %d", n)
+	}
+
+	letters, _ = sputnik.ListDeadLetters(ctx, dlbc)
+	nils := 0
+	for _, dl := range letters {
+		if dl.Msg == nil {
+			nils++
+			if dl.To != "1" {
+				t.Errorf("letter without message was sent again %+v", dl)
+			}
+		}
+	}
+	if nils != 1 {
+		t.Errorf("expected 1 letter without message, got %v", letters)
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestDeadLettersDropped(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	reg := tb.registry()
+	tb.register(reg, "newest", sputnik.WithMailbox(1, sputnik.OverflowDropNewest))
+	tb.register(reg, "oldest", sputnik.WithMailbox(1, sputnik.OverflowDropOldest))
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "dumb", Responsibility: "1"},
+		{Name: "newest", Responsibility: "n"},
+		{Name: "oldest", Responsibility: "o"},
+	}
+
+	// Dead letters are forwarded to the test
+	dlbd := sputnik.BlockDescriptor{Name: "dumb", Responsibility: "dl"}
+
+	// Sender of dropped message is known for traced messages
+	tb.fly(t, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks), sputnik.WithDeadLetters(dlbd), sputnik.WithTraceSink(new(testSink)))
+
+	letter := func() sputnik.DeadLetter {
+		t.Helper()
+		msg := tb.receive(t)
+		dl, ok := msg["__letter"].(sputnik.DeadLetter)
+		if !ok {
+			t.Fatalf("expected dead letter, got %v", msg)
+		}
+		return dl
+	}
+
+	// Worker of the block is busy, mailbox is full
+	hold := func(resp string, gate chan struct{}) {
+		t.Helper()
+		entered := make(chan struct{})
+		tb.sendTo(resp, sputnik.Msg{"__name": "slow", "entered": entered, "wait": gate})
+		<-entered
+	}
+
+	// Responsibility of the block used by sendTo
+	sender := tb.block(0).bc().Descriptor().Responsibility
+
+	ngate := make(chan struct{})
+	hold("n", ngate)
+	tb.sendTo("n", sputnik.Msg{"__name": "kept"})
+	tb.sendTo("n", sputnik.Msg{"__name": "dropped"})
+
+	if dl := letter(); dl.To != "n" || dl.From != sender || dl.Msg["__name"] != "dropped" || !strings.Contains(dl.Reason, "full") {
+		t.Errorf("unexpected dead letter of OverflowDropNewest %+v", dl)
+	}
+
+	ogate := make(chan struct{})
+	hold("o", ogate)
+	tb.sendTo("o", sputnik.Msg{"__name": "old"})
+	tb.sendTo("o", sputnik.Msg{"__name": "new"})
+
+	if dl := letter(); dl.To != "o" || dl.Msg["__name"] != "old" || !strings.Contains(dl.Reason, "full") {
+		t.Errorf("unexpected dead letter of OverflowDropOldest %+v", dl)
+	}
+
+	close(ogate)
+	if !tb.expect(1, "slow") || !tb.expect(1, "new") {
+		t.Errorf("the newest message should be processed")
+	}
+
+	// Pending message of removed block
+	bm, _ := tb.mainCntrl().(sputnik.BlocksManager)
+	removed := make(chan error, 1)
+	go func() {
+		removed <- bm.RemoveBlock("n")
+	}()
+
+	if dl := letter(); dl.To != "n" || dl.Msg["__name"] != "kept" || !strings.Contains(dl.Reason, "finished") {
+		t.Errorf("unexpected dead letter of discarded message %+v", dl)
+	}
+
+	close(ngate)
+	if err := <-removed; err != nil {
+		t.Errorf("RemoveBlock error %v", err)
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestReadyNoticeFullMailbox(t *testing.T) {
+
+	tb := NewTestBlocks()
+	gate := make(chan struct{})
+
+	// Mailbox of the block is full before readiness of the process
+	reg := tb.registry()
+	reg.Register("full", func() *sputnik.Block {
+		dmb, blk := tb.dumb()
+		for _, opt := range []sputnik.BlockOption{
+			sputnik.WithMailbox(1, sputnik.OverflowBlock),
+			sputnik.WithReadyNotice(),
+			sputnik.WithStart(func(bc sputnik.BlockCommunicator) {
+				dmb.start(bc)
+				holdOnMsg(bc, "gate", gate)
+				bc.Send(sputnik.Msg{"__name": "filler"})
+			}),
+		} {
+			opt(blk)
+		}
+		return blk
+	})
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "full", Responsibility: "full"},
+		{Name: "dumb", Responsibility: "1"},
+	}
+
+	tb.fly(t, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks))
+
+	// Initiator is not blocked by notice
+	bm, _ := tb.mainCntrl().(sputnik.BlocksManager)
+	mustReturn(t, "RemoveBlock", func() {
+		if err := bm.RemoveBlock("1"); err != nil {
+			t.Errorf("RemoveBlock error %v", err)
+		}
+	})
+
+	close(gate)
+
+	if !tb.expect(1, "gate") || !tb.expect(1, sputnik.AllReadyMsgName) || !tb.expect(1, "filler") {
+		t.Errorf("notice should be received before queued message")
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestDeadLettersFullMailbox(t *testing.T) {
+
+	tb := NewTestBlocks()
+	sink := new(testSink)
+	gate := make(chan struct{})
+
+	reg := tb.registry()
+	tb.register(reg, "dl", sputnik.WithMailbox(1, sputnik.OverflowBlock))
+
+	tb.fly(t,
+		sputnik.WithRegistry(reg),
+		sputnik.WithDeadLetters(sputnik.BlockDescriptor{Name: "dl", Responsibility: "dl"}),
+		sputnik.WithTraceSink(sink))
+
+	// Fill mailbox of dead-letter block
+	dlbc, _ := tb.comm("dl")
+	holdOnMsg(dlbc, "gate", gate)
+	dlbc.Send(sputnik.Msg{"__name": "filler"})
+
+	bc3, _ := tb.comm("3")
+
+	bm, _ := tb.mainCntrl().(sputnik.BlocksManager)
+	if err := bm.RemoveBlock("3"); err != nil {
+		t.Fatalf("RemoveBlock error %v", err)
+	}
+
+	// Dead letter is dropped, sender is not blocked
+	mustReturn(t, "Send", func() {
+		if bc3.Send(sputnik.Msg{"__name": "lost"}) {
+			t.Errorf("send to removed block should fail")
+		}
+	})
+
+	sink.lock.Lock()
+	dropped := 0
+	for _, hop := range sink.hops {
+		if hop.To == "dl" && !hop.Delivered {
+			dropped++
+		}
+	}
+	sink.lock.Unlock()
+
+	if dropped != 1 {
+		t.Errorf("dropped dead letter was not traced")
+	}
+
+	close(gate)
+
+	if !tb.expect(1, "gate") || !tb.expect(1, "filler") {
+		t.Errorf("unexpected messages of dead-letter block")
+	}
+
+	tb.land()
+
+	return
+}
+
+// Fails the test if f does not return in time
+func mustReturn(t *testing.T, what string, f func()) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s is blocked", what)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	var lock sync.Mutex
+	var calls []string
+
+	record := func(s string) {
+		lock.Lock()
+		defer lock.Unlock()
+		calls = append(calls, s)
+	}
+
+	recorded := func() string {
+		lock.Lock()
+		defer lock.Unlock()
+		return strings.Join(calls, ",")
+	}
+
+	spmw := sputnik.Middleware{
+		OnMsg: func(bd sputnik.BlockDescriptor, next sputnik.OnMsg) sputnik.OnMsg {
+			return func(msg sputnik.Msg) {
+				if _, blocked := msg["blocked"]; blocked {
+					return // short-circuit
+				}
+				record("s" + bd.Responsibility)
+				next(msg)
+			}
+		},
+		Around: func(bd sputnik.BlockDescriptor, callback string, next func() error) error {
+			if callback == sputnik.InitCallback {
+				record("init" + bd.Responsibility)
+			}
+			return next()
+		},
+	}
+
+	blmw := sputnik.Middleware{
+		OnMsg: func(bd sputnik.BlockDescriptor, next sputnik.OnMsg) sputnik.OnMsg {
+			return func(msg sputnik.Msg) {
+				record("b" + bd.Responsibility)
+				next(msg)
+			}
+		},
+	}
+
+	reg := tb.registry()
+	tb.register(reg, "mdumb", sputnik.WithBlockMiddleware(blmw))
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "mdumb", Responsibility: "1"},
+	}
+
+	tb.fly(t, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks), sputnik.WithMiddleware(spmw))
+
+	if recorded() != "init1" {
+		t.Errorf("middleware of sputnik should wrap Init of application block only, got %s", recorded())
+	}
+
+	bc := tb.block(0).bc()
+
+	bc.Send(sputnik.Msg{"__name": "blocked", "blocked": true})
+	bc.Send(sputnik.Msg{"__name": "passed"})
+
+	if !tb.expect(1, "passed") {
+		t.Errorf("blocked message should be short-circuited")
+	}
+
+	if got := recorded(); got != "init1,s1,b1" {
+		t.Errorf("unexpected order of middlewares %s", got)
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestWorkers(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	// Set after return of OnMsg for "hang" message
+	var handled atomic.Bool
+
+	// Closed after return of Finish callback of the block
+	finished := make(chan struct{})
+
+	mw := sputnik.Middleware{
+		OnMsg: func(bd sputnik.BlockDescriptor, next sputnik.OnMsg) sputnik.OnMsg {
+			return func(msg sputnik.Msg) {
+				next(msg)
+				if msg["__name"] == "hang" {
+					handled.Store(true)
+				}
+			}
+		},
+		Around: func(bd sputnik.BlockDescriptor, callback string, next func() error) error {
+			err := next()
+			if callback == sputnik.FinishCallback {
+				close(finished)
+			}
+			return err
+		},
+	}
+
+	key := func(msg sputnik.Msg) string {
+		key, _ := msg["key"].(string)
+		return key
+	}
+
+	reg := tb.registry()
+	tb.register(reg, "pool", sputnik.WithWorkers(4, key), sputnik.WithBlockMiddleware(mw))
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "pool", Responsibility: "pool"},
+	}
+
+	tb.fly(t, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks))
+
+	bc := tb.block(0).bc()
+
+	// Messages with the same key are processed in order
+	keys, count := 10, 20
+	for i := 0; i < count; i++ {
+		for k := 0; k < keys; k++ {
+			bc.Send(sputnik.Msg{"__name": "seq", "key": fmt.Sprintf("k%d", k), "seq": i})
+		}
+	}
+
+	last := make(map[string]int)
+	for i := 0; i < keys*count; i++ {
+		msg := tb.receive(t)
+		k, _ := msg["key"].(string)
+		seq, _ := msg["seq"].(int)
+		if prev, exists := last[k]; exists && seq != prev+1 {
+			t.Fatalf("key %s: message %d processed after %d", k, seq, prev)
+		}
+		last[k] = seq
+	}
+
+	// Worker is blocked by slow message, messages with other keys
+	// are not delayed by it
+	gate := make(chan struct{})
+	entered := make(chan struct{})
+	bc.Send(sputnik.Msg{"__name": "slow", "key": "slow", "entered": entered, "wait": gate})
+	<-entered
+	bc.Send(sputnik.Msg{"__name": "next", "key": "slow"})
+
+	fast := 20
+	for k := 0; k < fast; k++ {
+		bc.Send(sputnik.Msg{"__name": "fast", "key": fmt.Sprintf("f%d", k)})
+	}
+
+	if msg := tb.receive(t); msg["__name"] != "fast" {
+		t.Fatalf("expected message with another key, got %v", msg["__name"])
+	}
+
+	close(gate)
+
+	names := make([]string, 0)
+	for i := 0; i < fast+1; i++ {
+		name, _ := tb.receive(t)["__name"].(string)
+		if name != "fast" {
+			names = append(names, name)
+		}
+	}
+
+	if strings.Join(names, ",") != "slow,next" {
+		t.Errorf("messages with the same key should be processed in order, got %v", names)
+	}
+
+	// Finish waits for in-flight OnMsg
+	hang := make(chan struct{})
+	entered = make(chan struct{})
+	bc.Send(sputnik.Msg{"__name": "hang", "key": "hang", "entered": entered, "wait": hang})
+	<-entered
+
+	landed := make(chan struct{})
+	go func() {
+		defer close(landed)
+		tb.land()
+	}()
+
+	<-finished
+
+	select {
+	case <-landed:
+		t.Errorf("process finished before return of in-flight OnMsg")
+	default:
+	}
+
+	close(hang)
+	<-landed
+
+	if !handled.Load() {
+		t.Errorf("process finished before return of in-flight OnMsg")
+	}
+
+	return
+}
+
+func TestWorkersBusyKey(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	key := func(msg sputnik.Msg) string {
+		key, _ := msg["key"].(string)
+		return key
+	}
+
+	reg := tb.registry()
+	tb.register(reg, "pool", sputnik.WithWorkers(2, key), sputnik.WithMailbox(4, sputnik.OverflowReject))
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "pool", Responsibility: "pool"},
+	}
+
+	tb.fly(t, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks))
+
+	bc := tb.block(0).bc()
+
+	gate := make(chan struct{})
+	entered := make(chan struct{})
+	bc.Send(sputnik.Msg{"__name": "slow", "key": "slow", "entered": entered, "wait": gate})
+	<-entered
+
+	// Queue of blocked worker is full
+	next := 0
+	for bc.Send(sputnik.Msg{"__name": "next", "key": "slow"}) {
+		next++
+		if next > 4 {
+			t.Fatalf("mailbox with capacity 4 accepted %d messages", next)
+		}
+	}
+
+	// Messages of another worker are accepted and processed
+	fast := 0
+	for k := 0; k < 20; k++ {
+		if bc.Send(sputnik.Msg{"__name": "fast", "key": fmt.Sprintf("f%d", k)}) {
+			fast++
+		}
+	}
+
+	if fast == 0 {
+		t.Fatalf("full queue of busy worker should not reject messages of other workers")
+	}
+
+	if msg := tb.receive(t); msg["__name"] != "fast" {
+		t.Fatalf("expected message with another key, got %v", msg["__name"])
+	}
+
+	close(gate)
+
+	names := make([]string, 0)
+	for i := 0; i < fast-1+1+next; i++ {
+		name, _ := tb.receive(t)["__name"].(string)
+		if name != "fast" {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) != next+1 || names[0] != "slow" {
+		t.Errorf("messages with the same key should be processed in order, got %v", names)
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestWorkersUnordered(t *testing.T) {
+
+	tb := NewTestBlocks()
+
+	reg := tb.registry()
+	tb.register(reg, "pool", sputnik.WithWorkers(2, nil))
+
+	blocks := []sputnik.BlockDescriptor{
+		{Name: "pool", Responsibility: "pool"},
+	}
+
+	tb.fly(t, sputnik.WithRegistry(reg), sputnik.WithAppBlocks(blocks))
+
+	bc := tb.block(0).bc()
+
+	// The next message is processed by free worker
+	gate := make(chan struct{})
+	entered := make(chan struct{})
+	bc.Send(sputnik.Msg{"__name": "slow", "entered": entered, "wait": gate})
+	<-entered
+
+	count := 10
+	for i := 0; i < count; i++ {
+		bc.Send(sputnik.Msg{"__name": "fast"})
+	}
+
+	if !tb.expect(count, "fast") {
+		t.Errorf("messages should be processed by free worker")
+	}
+
+	close(gate)
+
+	if !tb.expect(1, "slow") {
+		t.Errorf("slow message was not processed")
+	}
+
+	tb.land()
+
+	return
+}
+
+func TestDescriptorJSON(t *testing.T) {
+
+	bd := sputnik.BlockDescriptor{
+		Name:           "dumb",
+		Responsibility: "1",
+		FinishTimeout:  5 * time.Second,
+		Restart:        sputnik.RestartPolicy{Mode: sputnik.RestartAlways, Backoff: 500 * time.Millisecond},
+		DependsOn:      []string{"2"},
+	}
+
+	raw, err := json.Marshal(bd)
+	if err != nil {
+		t.Fatalf("Marshal error %v", err)
+	}
+
+	if !strings.Contains(string(raw), `"FinishTimeout":"5s"`) || !strings.Contains(string(raw), `"Backoff":"500ms"`) {
+		t.Errorf("durations should be encoded as strings: %s", raw)
+	}
+
+	var got sputnik.BlockDescriptor
+	if err = json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("Unmarshal error %v", err)
+	}
+
+	if got.ID() != bd.ID() || got.FinishTimeout != bd.FinishTimeout || got.Restart != bd.Restart || got.DependsOn[0] != "2" {
+		t.Errorf("unexpected descriptor %+v", got)
+	}
+
+	// Numbers (nanoseconds) are still accepted
+	if err = json.Unmarshal([]byte(`{"Name":"dumb","FinishTimeout":1000000000}`), &got); err != nil || got.FinishTimeout != time.Second {
+		t.Errorf("unexpected FinishTimeout %v error %v", got.FinishTimeout, err)
+	}
+
+	if err = json.Unmarshal([]byte(`{"Name":"dumb","FinishTimeout":"soon"}`), &got); err == nil {
+		t.Errorf("expected error for wrong duration")
+	}
+
+	return
+}
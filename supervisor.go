@@ -0,0 +1,274 @@
+package sputnik
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RestartMode defines when supervisor re-creates the block
+type RestartMode string
+
+const (
+	// Block is never restarted (default)
+	RestartNever RestartMode = "never"
+
+	// Block is restarted after panic within any of it's callbacks
+	RestartOnFailure RestartMode = "on-failure"
+
+	// Block is restarted after panic or after return from Run
+	// before finish of the block
+	RestartAlways RestartMode = "always"
+)
+
+// RestartPolicy of the block.
+// Restart of the block:
+//   - Finish of the failed block
+//   - Creation of the new block using BlockFactory
+//   - Init
+//   - Run
+//   - OnServerConnect, if server is connected
+//
+// If number of restarts within Window exceeds MaxRestarts,
+// supervisor gives up and process is shut down.
+type RestartPolicy struct {
+	Mode RestartMode `json:",omitempty"`
+
+	// 0 - unlimited
+	MaxRestarts int `json:",omitempty"`
+
+	// 0 - restarts are counted during whole life of the process.
+	// In blocks.json - duration string, e.g. "1m".
+	Window time.Duration `json:",omitempty"`
+
+	// Delay between finish of the failed block and creation of the new one.
+	// In blocks.json - duration string, e.g. "500ms".
+	Backoff time.Duration `json:",omitempty"`
+}
+
+func (rp RestartPolicy) MarshalJSON() ([]byte, error) {
+	type plain RestartPolicy
+	return json.Marshal(struct {
+		plain
+		Window  jsonDuration `json:",omitempty"`
+		Backoff jsonDuration `json:",omitempty"`
+	}{plain(rp), jsonDuration(rp.Window), jsonDuration(rp.Backoff)})
+}
+
+func (rp *RestartPolicy) UnmarshalJSON(data []byte) error {
+	type plain RestartPolicy
+	aux := struct {
+		*plain
+		Window  jsonDuration `json:",omitempty"`
+		Backoff jsonDuration `json:",omitempty"`
+	}{plain: (*plain)(rp)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	rp.Window = time.Duration(aux.Window)
+	rp.Backoff = time.Duration(aux.Backoff)
+	return nil
+}
+
+func (rp RestartPolicy) restartOn(failure bool) bool {
+	switch rp.Mode {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return failure
+	}
+	return false
+}
+
+// Returns true if failure of the block is handled by supervisor
+func (inr *initiator) supervise(abl *activeBlock, failure bool) bool {
+	if inr.finishStarted || inr.isFinishing(abl) {
+		return false
+	}
+
+	if !abl.descriptor.Restart.restartOn(failure) {
+		return false
+	}
+
+	if !inr.allowRestart(abl) {
+		inr.giveUp(abl) // Restart intensity exceeded - escalate
+		return true
+	}
+
+	inr.restartBlock(abl)
+
+	return true
+}
+
+// Finishes the block, new one will be created after finish
+func (inr *initiator) restartBlock(abl *activeBlock) {
+	inr.restarting[abl.descriptor.Responsibility] = true
+	inr.finishBlock(abl)
+}
+
+func (inr *initiator) allowRestart(abl *activeBlock) bool {
+	rp := abl.descriptor.Restart
+	now := time.Now()
+
+	if rp.Window > 0 {
+		actual := make([]time.Time, 0, len(abl.restarts))
+		for _, rt := range abl.restarts {
+			if now.Sub(rt) < rp.Window {
+				actual = append(actual, rt)
+			}
+		}
+		abl.restarts = actual
+	}
+
+	if rp.MaxRestarts > 0 && len(abl.restarts) >= rp.MaxRestarts {
+		return false
+	}
+
+	abl.restarts = append(abl.restarts, now)
+	return true
+}
+
+func (inr *initiator) giveUp(abl *activeBlock) {
+	inr.shutdown(ExitRestartsExceeded, "",
+		fmt.Errorf("[%s,%s] exceeded %d restarts", abl.descriptor.Name, abl.descriptor.Responsibility, abl.descriptor.Restart.MaxRestarts))
+}
+
+// Old block finished, start of the new one after backoff
+func (inr *initiator) scheduleRestart(abl *activeBlock) {
+	resp := abl.descriptor.Responsibility
+	time.AfterFunc(abl.descriptor.Restart.Backoff, func() {
+		inr.put(restartmsg(resp))
+	})
+}
+
+// Creates and initializes new instance of the block on own goroutine,
+// main loop is not blocked by Init of the block
+func (inr *initiator) processRestart(m Msg) {
+	resp, _ := m["__resp"].(string)
+
+	abl, exists := inr.actBlks.getABl(resp)
+	if !exists || inr.finishStarted || !inr.restarting[resp] {
+		return
+	}
+
+	go func() {
+		nabl, err := inr.sputnik.createByDescr(abl.descriptor)
+		if err == nil {
+			err = inr.initBlock(nabl)
+		}
+		if err != nil {
+			nabl = nil
+		}
+
+		rm := blockrestartedmsg(abl, nabl, err)
+		if inr.q.put(context.Background(), rm, PriorityHigh) != nil {
+			inr.discardMsg(rm)
+		}
+	}()
+}
+
+// Runs initialized instance instead of finished one.
+// New instance is finished if process started shutdown or block was removed during Init.
+func (inr *initiator) processBlockRestarted(m Msg) {
+	abl, _ := m["__old"].(*activeBlock)
+	nabl, _ := m["__abl"].(*activeBlock)
+	err, _ := m["__err"].(error)
+
+	resp := abl.descriptor.Responsibility
+
+	if cabl, exists := inr.actBlks.getABl(resp); !exists || cabl != abl || inr.finishStarted || !inr.restarting[resp] {
+		if nabl != nil {
+			go inr.finishInitialized(nabl)
+		}
+		return
+	}
+
+	if err != nil {
+		// Failed creation is failure of the block
+		inr.addBlockError(err)
+		if !inr.allowRestart(abl) {
+			inr.giveUp(abl)
+			return
+		}
+		inr.scheduleRestart(abl)
+		return
+	}
+
+	delete(inr.restarting, resp)
+
+	abl.replace(nabl)
+	attachController(resp, inr.actBlks)
+
+	inr.Lock()
+	delete(inr.finished, resp)
+	inr.Unlock()
+	delete(inr.finishing, resp)
+
+	// Hung instance was replaced by the new one
+	inr.forgetAbandoned(resp)
+
+	inr.runBlock(abl)
+
+	if inr.connected {
+		inr.connectBlock(abl, inr.conn)
+	}
+	return
+}
+
+func (inr *initiator) processRunReturned(m Msg) {
+	abl, ok := inr.currentBlock(m)
+	if !ok {
+		return
+	}
+
+	inr.supervise(abl, false)
+	return
+}
+
+// Returns active block if message was sent by current instance of the block
+func (inr *initiator) currentBlock(m Msg) (*activeBlock, bool) {
+	resp, _ := m["__resp"].(string)
+
+	abl, exists := inr.actBlks.getABl(resp)
+	if !exists {
+		return nil, false
+	}
+
+	if cn, ok := m["__cntr"].(*controller); ok && cn != abl.controller {
+		return nil, false
+	}
+
+	return abl, true
+}
+
+const (
+	restartMsg        = "restart"
+	blockRestartedMsg = "blockRestarted"
+	runReturnedMsg    = "runReturned"
+)
+
+func restartmsg(resp string) Msg {
+	msg := make(Msg)
+	msg["__name"] = restartMsg
+	msg["__resp"] = resp
+	return msg
+}
+
+func blockrestartedmsg(abl *activeBlock, nabl *activeBlock, err error) Msg {
+	msg := make(Msg)
+	msg["__name"] = blockRestartedMsg
+	msg["__old"] = abl
+	msg["__abl"] = nabl
+	msg["__err"] = err
+	return msg
+}
+
+func runreturnedmsg(cn *controller) Msg {
+	msg := make(Msg)
+	msg["__name"] = runReturnedMsg
+	msg["__resp"] = cn.descriptor.Responsibility
+	msg["__cntr"] = cn
+	return msg
+}
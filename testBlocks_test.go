@@ -1,6 +1,10 @@
 package sputnik_test
 
 import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
 	"time"
 
 	"github.com/g41797/kissngoqueue"
@@ -10,15 +14,22 @@ import (
 // Test helper:
 type testBlocks struct {
 	// All blocks
-	dbl []*dumbBlock
+	lock sync.Mutex
+	dbl  []*dumbBlock
 	// Test queue
 	q *kissngoqueue.Queue[sputnik.Msg]
+	// Queue is attached to blocks created after attachQueue
+	attached bool
+	// Every created block, used for waiting of re-created blocks
+	created chan *dumbBlock
 	// Launcher
 	launch sputnik.Launch
 	// ShootDown
 	kill sputnik.ShootDown
 	// Signalling channel
 	done chan struct{}
+	// Result of Launch, valid after close of done
+	err error
 	// ServerConnector
 	conntr sputnik.DummyConnector
 	to     time.Duration
@@ -29,6 +40,7 @@ func NewTestBlocks() *testBlocks {
 	tb.q = kissngoqueue.NewQueue[sputnik.Msg]()
 	tb.conntr = sputnik.DummyConnector{}
 	tb.to = time.Millisecond * 100
+	tb.created = make(chan *dumbBlock, 64)
 	return tb
 }
 
@@ -63,12 +75,30 @@ func (tb *testBlocks) expect(n int, name string) bool {
 	return true
 }
 
+// Waits the next message from blocks
+func (tb *testBlocks) receive(t *testing.T) sputnik.Msg {
+	t.Helper()
+
+	received := make(chan sputnik.Msg, 1)
+	go func() {
+		msg, _ := tb.q.Get()
+		received <- msg
+	}()
+
+	select {
+	case msg := <-received:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatalf("message was not received")
+	}
+	return nil
+}
+
 // Send msg to block using it's responsibility
 // Use this pattern in real application for
 // negotiation between blocks
 func (tb *testBlocks) sendTo(resp string, msg sputnik.Msg) bool {
-	cn := tb.dbl[0].communicator
-	bc, exists := cn.Communicator(resp)
+	bc, exists := tb.comm(resp)
 
 	if !exists {
 		return false
@@ -77,11 +107,29 @@ func (tb *testBlocks) sendTo(resp string, msg sputnik.Msg) bool {
 	return sok
 }
 
+// Communicator of the block with responsibility resp
+func (tb *testBlocks) comm(resp string) (sputnik.BlockCommunicator, bool) {
+	return tb.block(0).bc().Communicator(resp)
+}
+
 func (tb *testBlocks) mainCntrl() sputnik.BlockCommunicator {
-	mcn, _ := tb.dbl[0].communicator.Communicator(sputnik.InitiatorResponsibility)
+	mcn, _ := tb.comm(sputnik.InitiatorResponsibility)
 	return mcn
 }
 
+// i-th created block
+func (tb *testBlocks) block(i int) *dumbBlock {
+	tb.lock.Lock()
+	defer tb.lock.Unlock()
+	return tb.dbl[i]
+}
+
+func (tb *testBlocks) blocks() int {
+	tb.lock.Lock()
+	defer tb.lock.Unlock()
+	return len(tb.dbl)
+}
+
 // Run Launcher on dedicated goroutine
 // Test controls execution via sputnik API
 // Results received using queue
@@ -90,12 +138,75 @@ func (tb *testBlocks) run() {
 
 	go func(l sputnik.Launch, done chan struct{}) {
 		defer close(done)
-		l()
+		tb.err = l()
 	}(tb.launch, tb.done)
 
 	return
 }
 
+// Prepares sputnik (see dumbSputnik), connects to server, launches the flight
+// and waits readiness of all blocks.
+// OnServerConnect notifications of dumb blocks are consumed.
+func (tb *testBlocks) fly(t *testing.T, opts ...sputnik.SputnikOption) *sputnik.Flight {
+	t.Helper()
+	return tb.flyContext(t, context.Background(), opts...)
+}
+
+func (tb *testBlocks) flyContext(t *testing.T, ctx context.Context, opts ...sputnik.SputnikOption) *sputnik.Flight {
+	t.Helper()
+
+	dsp := dumbSputnik(tb, opts...)
+
+	fl, err := dsp.PrepareContext(ctx)
+	if err != nil {
+		t.Fatalf("PrepareContext error %v", err)
+	}
+
+	tb.attachQueue()
+	tb.launch = fl.Launch
+	tb.kill = fl.ShootDown
+	tb.conntr.SetState(true)
+
+	tb.run()
+
+	rctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err = fl.WaitReady(rctx); err != nil {
+		t.Fatalf("WaitReady error %v", err)
+	}
+
+	if !tb.expect(tb.blocks(), "serverConnected") {
+		t.Fatalf("Wrong processing of serverconnected")
+	}
+
+	// Blocks of the flight were created, see next
+	for len(tb.created) > 0 {
+		<-tb.created
+	}
+
+	return fl
+}
+
+// ShootDown of the flight and wait of return from Launch
+func (tb *testBlocks) land() {
+	tb.kill()
+	<-tb.done
+}
+
+// Waits creation of the next block, e.g. by supervisor
+func (tb *testBlocks) next(t *testing.T) *dumbBlock {
+	t.Helper()
+
+	select {
+	case dmb := <-tb.created:
+		return dmb
+	case <-time.After(5 * time.Second):
+		t.Fatalf("block was not created")
+	}
+	return nil
+}
+
 // Registration of factories for test environment
 // For this case init() isn't used
 // use this pattern for the case when you don't need
@@ -122,44 +233,187 @@ func (tb *testBlocks) factories() sputnik.BlockFactories {
 	return res
 }
 
+// Registry with factories of test environment
+func (tb *testBlocks) registry() *sputnik.Registry {
+	return sputnik.NewRegistryFrom(tb.factories())
+}
+
+// Registers factory of dumb block with additional options
+func (tb *testBlocks) register(reg *sputnik.Registry, name string, opts ...sputnik.BlockOption) {
+	reg.Register(name, func() *sputnik.Block {
+		blk := tb.dbFact()
+		for _, opt := range opts {
+			opt(blk)
+		}
+		return blk
+	})
+}
+
 func (tb *testBlocks) attachQueue() {
+	tb.lock.Lock()
+	defer tb.lock.Unlock()
+
+	tb.attached = true
 	for i, _ := range tb.dbl {
 		tb.dbl[i].q = tb.q
 	}
 	return
 }
 
+// Registers factory of dumb blocks, which record Init and Finish in the journal.
+// Optional hook is called within Init of the block, error of the hook fails Init.
+func (tb *testBlocks) registerRecorder(reg *sputnik.Registry, name string, jr *journal, hook func(resp string) error) {
+	reg.RegisterParam(name, func(bd sputnik.BlockDescriptor, _ sputnik.BlockOptions) (*sputnik.Block, error) {
+		dmb, blk := tb.dumb()
+		resp := bd.Responsibility
+
+		sputnik.WithInit(func(cf sputnik.ConfFactory) error {
+			jr.add("init>" + resp)
+			defer jr.add("init<" + resp)
+			if hook != nil {
+				if err := hook(resp); err != nil {
+					return err
+				}
+			}
+			return dmb.init(cf)
+		})(blk)
+
+		sputnik.WithFinish(func(init bool) {
+			jr.add(fmt.Sprintf("finish(%t)>%s", init, resp))
+			defer jr.add(fmt.Sprintf("finish(%t)<%s", init, resp))
+			dmb.finish(init)
+		})(blk)
+
+		return blk, nil
+	})
+}
+
 // Block factory:
 func (tb *testBlocks) dbFact() *sputnik.Block {
+	_, blk := tb.dumb()
+	return blk
+}
+
+func (tb *testBlocks) dumb() (*dumbBlock, *sputnik.Block) {
 	dmb := new(dumbBlock)
+	dmb.ready = make(chan struct{})
+
+	tb.lock.Lock()
+	if tb.attached {
+		dmb.q = tb.q
+	}
 	tb.dbl = append(tb.dbl, dmb)
-	return sputnik.NewBlock(
+	tb.lock.Unlock()
+
+	select {
+	case tb.created <- dmb:
+	default:
+	}
+
+	return dmb, sputnik.NewBlock(
 		sputnik.WithInit(dmb.init),
+		sputnik.WithStart(dmb.start),
 		sputnik.WithRun(dmb.run),
 		sputnik.WithFinish(dmb.finish),
 
 		sputnik.WithOnMsg(dmb.eventReceived),
 		sputnik.WithOnConnect(dmb.serverConnected),
 		sputnik.WithOnDisconnect(dmb.serverDisconnected),
+		sputnik.WithOnConfigChange(dmb.configChanged),
 	)
 }
 
 // Satellite has 3 app. blocks:
 var blkList []sputnik.BlockDescriptor = []sputnik.BlockDescriptor{
-	{"dumb", "1"},
-	{"dumb", "2"},
-	{"dumb", "3"},
+	{Name: "dumb", Responsibility: "1"},
+	{Name: "dumb", Responsibility: "2"},
+	{Name: "dumb", Responsibility: "3"},
 }
 
 // Configuration factory:
 func dumbConf(confName string, result any) error { return nil }
 
-func dumbSputnik(tb *testBlocks) sputnik.Sputnik {
-	sp, _ := sputnik.NewSputnik(
+func dumbSputnik(tb *testBlocks, opts ...sputnik.SputnikOption) sputnik.Sputnik {
+	opts = append([]sputnik.SputnikOption{
 		sputnik.WithConfFactory(dumbConf),
 		sputnik.WithAppBlocks(blkList),
 		sputnik.WithBlockFactories(tb.factories()),
 		sputnik.WithConnector(&tb.conntr, tb.to),
-	)
+	}, opts...)
+
+	sp, _ := sputnik.NewSputnik(opts...)
 	return *sp
 }
+
+// Observer of life cycle events, used for synchronization with sputnik
+type eventsObserver struct {
+	events chan sputnik.LifecycleEvent
+}
+
+func newEventsObserver() *eventsObserver {
+	return &eventsObserver{events: make(chan sputnik.LifecycleEvent, 1024)}
+}
+
+func (eo *eventsObserver) Observe(ev sputnik.LifecycleEvent) {
+	select {
+	case eo.events <- ev:
+	default:
+	}
+}
+
+// Waits event of the block with responsibility resp
+func (eo *eventsObserver) wait(t *testing.T, kind sputnik.LifecycleEventKind, resp string) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-eo.events:
+			if ev.Kind == kind && ev.Descriptor.Responsibility == resp {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("%s of block %s was not observed", kind, resp)
+		}
+	}
+}
+
+// Journal of callbacks, e.g. "init>1" - start of Init, "init<1" - return from Init
+type journal struct {
+	lock    sync.Mutex
+	entries []string
+}
+
+func (jr *journal) add(entry string) {
+	jr.lock.Lock()
+	defer jr.lock.Unlock()
+	jr.entries = append(jr.entries, entry)
+}
+
+// Position of the entry, -1 if it's absent
+func (jr *journal) index(entry string) int {
+	jr.lock.Lock()
+	defer jr.lock.Unlock()
+	for i, e := range jr.entries {
+		if e == entry {
+			return i
+		}
+	}
+	return -1
+}
+
+func (jr *journal) list() []string {
+	jr.lock.Lock()
+	defer jr.lock.Unlock()
+	return append([]string(nil), jr.entries...)
+}
+
+// Checks that both entries exist and first precedes second
+func (jr *journal) before(t *testing.T, first, second string) {
+	t.Helper()
+
+	fi, si := jr.index(first), jr.index(second)
+	if fi < 0 || si < 0 || fi > si {
+		t.Errorf("%s should precede %s, journal %v", first, second, jr.list())
+	}
+}
@@ -0,0 +1,145 @@
+package sputnik
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Timers of the block, owned by controller.
+// All timers are cancelled when the block is finished.
+type blockTimers struct {
+	lock    sync.Mutex
+	stops   map[chan struct{}]struct{}
+	stopped bool
+	// Counts running timers
+	wg sync.WaitGroup
+}
+
+func newBlockTimers() *blockTimers {
+	return &blockTimers{stops: make(map[chan struct{}]struct{})}
+}
+
+// Runs timer on dedicated goroutine, returns cancel of the timer
+func (bt *blockTimers) start(run func(stop <-chan struct{})) (cancel func()) {
+	bt.lock.Lock()
+	defer bt.lock.Unlock()
+
+	if bt.stopped {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	bt.stops[stop] = struct{}{}
+
+	cancel = func() {
+		bt.lock.Lock()
+		defer bt.lock.Unlock()
+
+		if _, exists := bt.stops[stop]; exists {
+			delete(bt.stops, stop)
+			close(stop)
+		}
+	}
+
+	bt.wg.Add(1)
+	go func() {
+		defer bt.wg.Done()
+		run(stop)
+		cancel()
+	}()
+
+	return cancel
+}
+
+func (bt *blockTimers) stopAll() {
+	bt.lock.Lock()
+	defer bt.lock.Unlock()
+
+	bt.stopped = true
+	for stop := range bt.stops {
+		close(stop)
+	}
+	bt.stops = make(map[chan struct{}]struct{})
+}
+
+// Waits for return of stopped timers, e.g. send in progress
+func (bt *blockTimers) wait() {
+	bt.wg.Wait()
+}
+
+// Sends copies of msg to controlled block at times returned by next.
+// Zero time means end of the schedule.
+func (cn *controller) schedule(from sender, msg Msg, next func(now time.Time) time.Time) (cancel func()) {
+	return cn.timers.start(func(stop <-chan struct{}) {
+		for {
+			now := time.Now()
+			at := next(now)
+			if at.IsZero() {
+				return
+			}
+
+			tm := time.NewTimer(at.Sub(now))
+
+			select {
+			case <-tm.C:
+				cn.sendFrom(from, copymsg(msg))
+			case <-stop:
+				tm.Stop()
+				return
+			}
+		}
+	})
+}
+
+// SendAfter sends msg to controlled block after d
+func (cn *controller) SendAfter(d time.Duration, msg Msg) (cancel func()) {
+	return cn.sendAfterFrom(cn.self(), d, msg)
+}
+
+func (cn *controller) sendAfterFrom(from sender, d time.Duration, msg Msg) (cancel func()) {
+	fired := false
+	return cn.schedule(from, msg, func(now time.Time) time.Time {
+		if fired {
+			return time.Time{}
+		}
+		fired = true
+		return now.Add(d)
+	})
+}
+
+// SendEvery sends msg to controlled block every interval
+func (cn *controller) SendEvery(interval time.Duration, msg Msg) (cancel func()) {
+	return cn.sendEveryFrom(cn.self(), interval, msg)
+}
+
+func (cn *controller) sendEveryFrom(from sender, interval time.Duration, msg Msg) (cancel func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	at := time.Now()
+	return cn.schedule(from, msg, func(now time.Time) time.Time {
+		at = at.Add(interval)
+		if at.Before(now) {
+			at = now // slow consumer - skip missed ticks
+		}
+		return at
+	})
+}
+
+// SendCron sends msg to controlled block according to cron expression, e.g.
+//
+//	cancel, err := bc.SendCron("*/5 * * * *", sputnik.Msg{"cmd": "flush"}) // every 5 minutes
+func (cn *controller) SendCron(spec string, msg Msg) (cancel func(), err error) {
+	return cn.sendCronFrom(cn.self(), spec, msg)
+}
+
+func (cn *controller) sendCronFrom(from sender, spec string, msg Msg) (cancel func(), err error) {
+	cs, err := parseCron(spec)
+	if err != nil {
+		return nil, fmt.Errorf("SendCron [%s]: %w", cn.descriptor.Responsibility, err)
+	}
+
+	return cn.schedule(from, msg, cs.next), nil
+}
@@ -0,0 +1,276 @@
+package sputnik
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// If trace sink is set (see WithTraceSink), every message sent via BlockCommunicator
+// is stamped by sputnik:
+//   - "__id"          - unique id of the message
+//   - "__from"        - responsibility of the sender
+//   - "__correlation" - id of the first message of the chain
+//   - "__causation"   - id of the message processed by OnMsg of the sender during send
+//   - "__sent_at"     - time of the send
+//
+// Messages sent by the block on goroutine of OnMsg are stamped as caused by
+// the processed message and continue its chain. Messages sent on other goroutines
+// (Run, timers, goroutines started by OnMsg) start new chain, unless
+// "__correlation" of the sent message was set (e.g. forwarded copy of received message)
+// or the message was sent via communicator returned by CausedBy.
+//
+// Without trace sink messages are sent as is.
+
+// Record of one hop of the message
+type TraceHop struct {
+	ID          string
+	From        string
+	To          string
+	Correlation string
+	Causation   string
+	SentAt      time.Time
+	// false if the message was not accepted by mailbox of the recipient
+	Delivered bool
+}
+
+// TraceSink records hops of messages (see WithTraceSink).
+// Trace is called synchronously by the sender, don't block within Trace.
+type TraceSink interface {
+	Trace(hop TraceHop)
+}
+
+var (
+	msgSeq    uint64
+	msgPrefix = strconv.FormatInt(time.Now().UnixNano(), 36)
+)
+
+func newMsgID() string {
+	return msgPrefix + "-" + strconv.FormatUint(atomic.AddUint64(&msgSeq, 1), 36)
+}
+
+// Message, which caused the send
+type msgCause struct {
+	id          string
+	correlation string
+}
+
+func causeOf(msg Msg) *msgCause {
+	id, _ := msg["__id"].(string)
+	correlation, _ := msg["__correlation"].(string)
+	if id == "" && correlation == "" {
+		return nil
+	}
+	return &msgCause{id: id, correlation: correlation}
+}
+
+// Sender of the message: controlled block and optional cause of the send
+type sender struct {
+	*controller
+	cause *msgCause
+}
+
+func (cn *controller) self() sender {
+	return sender{controller: cn}
+}
+
+// CausedBy returns communicator, which stamps sent messages as caused by msg:
+// "__causation" - id of msg, "__correlation" - correlation of msg.
+// Sends on goroutine of OnMsg are stamped automatically,
+// use CausedBy for messages sent as result of processing of msg on another goroutine:
+//
+//	func (b *block) onMsg(msg sputnik.Msg) {
+//		bc := sputnik.CausedBy(b.bc, msg)
+//		go func() {
+//			next, _ := bc.Communicator("next")
+//			next.Send(process(msg))
+//		}()
+//	}
+//
+// Communicators returned by Communicator of the result are bound to the same cause.
+// Without trace sink and for other implementations of BlockCommunicator bc is returned.
+func CausedBy(bc BlockCommunicator, msg Msg) BlockCommunicator {
+	cause := causeOf(msg)
+	if cause == nil {
+		return bc
+	}
+
+	switch c := bc.(type) {
+	case *controller:
+		if !c.traced() {
+			return bc
+		}
+		return &peer{controller: c, from: sender{controller: c, cause: cause}}
+	case *peer:
+		if !c.traced() {
+			return bc
+		}
+		return &peer{controller: c.controller, from: sender{controller: c.from.controller, cause: cause}}
+	}
+	return bc
+}
+
+// Returns stamped copy of the message sent by the sender,
+// without trace sink the message itself
+func (from sender) stamp(msg Msg) Msg {
+	if !from.traced() {
+		return msg
+	}
+
+	m := copymsg(msg)
+
+	id := newMsgID()
+	m["__id"] = id
+	m["__from"] = from.descriptor.Responsibility
+	m["__sent_at"] = time.Now()
+
+	delete(m, "__causation")
+
+	cause := from.cause
+	if cause == nil {
+		cause = from.handling()
+	}
+	if cause != nil && cause.id != "" {
+		m["__causation"] = cause.id
+	}
+
+	if _, exists := m["__correlation"]; !exists {
+		if cause != nil && cause.correlation != "" {
+			m["__correlation"] = cause.correlation
+		} else {
+			m["__correlation"] = id
+		}
+	}
+
+	return m
+}
+
+// Responsibility of the sender, empty without trace sink:
+// communicators of other blocks are not bound to the sender
+func (from sender) responsibility() string {
+	if !from.traced() {
+		return ""
+	}
+	return from.descriptor.Responsibility
+}
+
+func (cn *controller) traced() bool {
+	return cn.actBlks.sink != nil
+}
+
+// Cause of the messages sent on goroutine of OnMsg
+func (cn *controller) handleMsg(msg Msg, handle func()) {
+	cause := causeOf(msg)
+	if !cn.traced() || cause == nil {
+		handle()
+		return
+	}
+
+	id := goid()
+	cn.causes.Store(id, cause)
+	cn.handlers.Add(1)
+
+	defer func() {
+		cn.handlers.Add(-1)
+		cn.causes.Delete(id)
+	}()
+
+	handle()
+}
+
+// Returns cause of the send on goroutine of OnMsg
+func (cn *controller) handling() *msgCause {
+	if cn.handlers.Load() == 0 {
+		return nil
+	}
+
+	cause, exists := cn.causes.Load(goid())
+	if !exists {
+		return nil
+	}
+	return cause.(*msgCause)
+}
+
+// Id of the current goroutine, parsed from "goroutine N [running]:"
+func goid() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// Reports hop of stamped message sent to controlled block
+func (cn *controller) trace(m Msg, delivered bool) {
+	ts := cn.actBlks.sink
+	if ts == nil {
+		return
+	}
+
+	hop := TraceHop{To: cn.descriptor.Responsibility, Delivered: delivered}
+	hop.ID, _ = m["__id"].(string)
+	hop.From, _ = m["__from"].(string)
+	hop.Correlation, _ = m["__correlation"].(string)
+	hop.Causation, _ = m["__causation"].(string)
+	hop.SentAt, _ = m["__sent_at"].(time.Time)
+
+	ts.Trace(hop)
+}
+
+// Communicator of another block, returned by Communicator if trace sink is set.
+// Messages sent via peer (including messages of timers) are stamped as sent by 'from'.
+type peer struct {
+	*controller
+	from sender
+}
+
+func (p *peer) Communicator(resp string) (bc BlockCommunicator, exists bool) {
+	return p.controller.communicatorFrom(p.from, resp)
+}
+
+func (p *peer) Send(msg Msg) bool {
+	return p.controller.sendFrom(p.from, msg)
+}
+
+func (p *peer) SendWithPriority(msg Msg, pr Priority) bool {
+	return p.controller.sendWithPriorityFrom(p.from, msg, pr)
+}
+
+func (p *peer) SendContext(ctx context.Context, msg Msg) error {
+	return p.controller.sendContextFrom(ctx, p.from, msg)
+}
+
+func (p *peer) Ask(ctx context.Context, msg Msg) (Msg, error) {
+	return p.controller.askFrom(ctx, p.from, msg)
+}
+
+func (p *peer) Publish(topic string, msg Msg) int {
+	return p.controller.publishFrom(p.from, topic, msg)
+}
+
+func (p *peer) Broadcast(msg Msg, filter BroadcastFilter) map[string]bool {
+	return p.controller.actBlks.broadcast(p.from, msg, filter)
+}
+
+func (p *peer) SendAfter(d time.Duration, msg Msg) (cancel func()) {
+	return p.controller.sendAfterFrom(p.from, d, msg)
+}
+
+func (p *peer) SendEvery(interval time.Duration, msg Msg) (cancel func()) {
+	return p.controller.sendEveryFrom(p.from, interval, msg)
+}
+
+func (p *peer) SendCron(spec string, msg Msg) (cancel func(), err error) {
+	return p.controller.sendCronFrom(p.from, spec, msg)
+}
+
+// Subscription belongs to controlled block, not to the sender
+func (p *peer) Subscribe(topic string) bool {
+	return p.controller.Subscribe(topic)
+}
@@ -0,0 +1,322 @@
+package sputnik
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Typed messages - Go values wrapped within Msg envelope:
+//   - "__name"    - registered name of the type
+//   - "__payload" - the value
+//
+// Envelope is regular Msg, so typed messages are sent via the same
+// BlockCommunicator and may be processed by plain OnMsg as well.
+// Names of the types are registered in Registry of the process.
+//
+//	func init() {
+//		sputnik.MustRegisterMsgType[Alert]("alert")
+//	}
+//
+//	// sender
+//	as, _ := sputnik.NewTypedSender[Alert](bc)
+//	as.Send(Alert{Level: 3})
+//
+//	// receiver
+//	md := sputnik.NewMsgDispatcher()
+//	md.UseRegistry(reg) // if types are registered in registry of the process
+//	sputnik.HandleTyped(md, func(a Alert) {...})
+//	blk := sputnik.NewBlock(..., sputnik.WithOnMsg(md.OnMsg))
+
+// Returned for message without typed handler
+var ErrUnknownMsgType = errors.New("unknown type of message")
+
+// Names of the types of registry
+type msgTypes struct {
+	lock  sync.RWMutex
+	names map[reflect.Type]string
+	types map[string]reflect.Type
+}
+
+func newMsgTypes() *msgTypes {
+	return &msgTypes{
+		names: make(map[reflect.Type]string),
+		types: make(map[string]reflect.Type),
+	}
+}
+
+func (mt *msgTypes) clone() *msgTypes {
+	mt.lock.RLock()
+	defer mt.lock.RUnlock()
+
+	result := newMsgTypes()
+	for tp, name := range mt.names {
+		result.names[tp] = name
+		result.types[name] = tp
+	}
+	return result
+}
+
+// Names of messages sent by sputnik
+var reservedMsgNames = map[string]bool{
+	AllReadyMsgName:       true,
+	DeadLetterMsgName:     true,
+	finishMsg:             true,
+	finishedMsg:           true,
+	serverConnectedMsg:    true,
+	serverDisconnectedMsg: true,
+	abandonedMsg:          true,
+	blockPanicMsg:         true,
+	blockReadyMsg:         true,
+	restartMsg:            true,
+	runReturnedMsg:        true,
+	addBlockMsg:           true,
+	blockAddedMsg:         true,
+	removeBlockMsg:        true,
+	configChangedMsg:      true,
+}
+
+func (mt *msgTypes) register(name string, tp reflect.Type) error {
+	if name == "" {
+		return fmt.Errorf("RegisterMsgType: empty name")
+	}
+
+	if strings.HasPrefix(name, "__") || reservedMsgNames[name] {
+		return fmt.Errorf("RegisterMsgType: name %s is reserved by sputnik", name)
+	}
+
+	mt.lock.Lock()
+	defer mt.lock.Unlock()
+
+	if rt, exists := mt.types[name]; exists {
+		if rt == tp {
+			return nil
+		}
+		return fmt.Errorf("RegisterMsgType: name %s already registered for %v", name, rt)
+	}
+
+	if rn, exists := mt.names[tp]; exists {
+		return fmt.Errorf("RegisterMsgType: %v already registered as %s", tp, rn)
+	}
+
+	mt.names[tp] = name
+	mt.types[name] = tp
+	return nil
+}
+
+func (mt *msgTypes) name(tp reflect.Type) (string, error) {
+	mt.lock.RLock()
+	defer mt.lock.RUnlock()
+
+	name, exists := mt.names[tp]
+	if !exists {
+		return "", fmt.Errorf("type %v: %w", tp, ErrUnknownMsgType)
+	}
+	return name, nil
+}
+
+func (mt *msgTypes) typeOf(name string) (reflect.Type, error) {
+	mt.lock.RLock()
+	defer mt.lock.RUnlock()
+
+	tp, exists := mt.types[name]
+	if !exists {
+		return nil, fmt.Errorf("message %q: %w", name, ErrUnknownMsgType)
+	}
+	return tp, nil
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// RegisterMsgType registers name of the type T for typed messages.
+// Names with prefix "__" and names of messages sent by sputnik are reserved.
+func RegisterMsgType[T any](r *Registry, name string) error {
+	return r.types.register(name, typeOf[T]())
+}
+
+// MustRegisterMsgType registers T in the global registry of the process.
+// Same rules as for RegisterBlockFactory - use init() for registration.
+func MustRegisterMsgType[T any](name string) {
+	if err := RegisterMsgType[T](DefaultRegistry(), name); err != nil {
+		panic(err)
+	}
+}
+
+// MsgTypeName returns registered name of the type T
+func MsgTypeName[T any](r *Registry) (string, error) {
+	return r.types.name(typeOf[T]())
+}
+
+// TypedMsg wraps v within Msg envelope
+func TypedMsg[T any](r *Registry, v T) (Msg, error) {
+	name, err := MsgTypeName[T](r)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := make(Msg)
+	msg["__name"] = name
+	msg["__payload"] = v
+	return msg, nil
+}
+
+// FromMsg extracts value of type T from Msg envelope
+func FromMsg[T any](msg Msg) (T, bool) {
+	v, ok := msg["__payload"].(T)
+	return v, ok
+}
+
+// TypedSender sends values of type T to the block
+type TypedSender[T any] struct {
+	bc   BlockCommunicator
+	name string
+}
+
+// NewTypedSender returns error if T was not registered in Registry of the process
+func NewTypedSender[T any](bc BlockCommunicator) (*TypedSender[T], error) {
+	name, err := MsgTypeName[T](registryOf(bc))
+	if err != nil {
+		return nil, err
+	}
+	return &TypedSender[T]{bc: bc, name: name}, nil
+}
+
+// Registry of the process of communicator,
+// global registry for other implementations of BlockCommunicator
+func registryOf(bc BlockCommunicator) *Registry {
+	if cn, ok := bc.(interface{ registry() *Registry }); ok {
+		if reg := cn.registry(); reg != nil {
+			return reg
+		}
+	}
+	return DefaultRegistry()
+}
+
+func (cn *controller) registry() *Registry {
+	return cn.actBlks.reg
+}
+
+func (ts *TypedSender[T]) Send(v T) bool {
+	return ts.bc.Send(ts.envelope(v))
+}
+
+func (ts *TypedSender[T]) SendContext(ctx context.Context, v T) error {
+	return ts.bc.SendContext(ctx, ts.envelope(v))
+}
+
+func (ts *TypedSender[T]) envelope(v T) Msg {
+	msg := make(Msg)
+	msg["__name"] = ts.name
+	msg["__payload"] = v
+	return msg
+}
+
+// MsgDispatcher calls typed handler according to registered name of the message
+// and type of the payload.
+// Use md.OnMsg as OnMsg callback of the block.
+type MsgDispatcher struct {
+	lock      sync.RWMutex
+	reg       *Registry
+	handlers  map[reflect.Type]func(Msg)
+	plain     OnMsg
+	onUnknown func(msg Msg, err error)
+}
+
+// Names of the types are looked up in the global registry (see UseRegistry)
+func NewMsgDispatcher() *MsgDispatcher {
+	return &MsgDispatcher{reg: DefaultRegistry(), handlers: make(map[reflect.Type]func(Msg))}
+}
+
+// Registry with names of the types, usually registry of the process (see WithRegistry)
+func (md *MsgDispatcher) UseRegistry(r *Registry) {
+	md.lock.Lock()
+	defer md.lock.Unlock()
+	md.reg = r
+}
+
+// HandleTyped registers handler of values of type T
+func HandleTyped[T any](md *MsgDispatcher, h func(v T)) {
+	md.lock.Lock()
+	defer md.lock.Unlock()
+
+	md.handlers[typeOf[T]()] = func(msg Msg) {
+		v, _ := FromMsg[T](msg)
+		h(v)
+	}
+}
+
+// Handler of messages without payload (plain Msg)
+func (md *MsgDispatcher) HandlePlain(h OnMsg) {
+	md.lock.Lock()
+	defer md.lock.Unlock()
+	md.plain = h
+}
+
+// Report of messages, which could not be dispatched
+func (md *MsgDispatcher) OnUnknown(f func(msg Msg, err error)) {
+	md.lock.Lock()
+	defer md.lock.Unlock()
+	md.onUnknown = f
+}
+
+// Dispatch calls handler of the message.
+// Error (ErrUnknownMsgType) is returned if there is no suitable handler,
+// name of typed message is not registered or was registered for another type.
+func (md *MsgDispatcher) Dispatch(msg Msg) error {
+	payload, typed := msg["__payload"]
+	name, _ := msg["__name"].(string)
+
+	md.lock.RLock()
+	reg := md.reg
+	plain := md.plain
+	md.lock.RUnlock()
+
+	if !typed {
+		if plain != nil {
+			plain(msg)
+			return nil
+		}
+		return fmt.Errorf("message %q: %w", name, ErrUnknownMsgType)
+	}
+
+	tp, err := reg.types.typeOf(name)
+	if err != nil {
+		return err
+	}
+
+	if reflect.TypeOf(payload) != tp {
+		return fmt.Errorf("message %q of %v with payload %T: %w", name, tp, payload, ErrUnknownMsgType)
+	}
+
+	md.lock.RLock()
+	h, exists := md.handlers[tp]
+	md.lock.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("message %q with payload %T: %w", name, payload, ErrUnknownMsgType)
+	}
+
+	h(msg)
+	return nil
+}
+
+// OnMsg callback of the block
+func (md *MsgDispatcher) OnMsg(msg Msg) {
+	err := md.Dispatch(msg)
+	if err == nil {
+		return
+	}
+
+	md.lock.RLock()
+	onUnknown := md.onUnknown
+	md.lock.RUnlock()
+
+	if onUnknown != nil {
+		onUnknown(msg, err)
+	}
+}